@@ -68,7 +68,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _templatesIndexHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x9c\x55\x5d\x6f\xb3\x36\x14\xbe\xe7\x57\x9c\xd7\xaa\x14\x92\x05\x93\xbc\xef\x3e\x3a\x15\x32\x55\x6d\x37\xed\xaa\xbd\xe8\xa4\x4d\xd3\x34\x39\x70\x02\x8e\x8c\x4d\x6d\x93\x34\x9a\xfa\xdf\x77\x80\xb0\xb0\xb5\x17\x13\x5c\x04\xfb\xf8\x79\x9e\xf3\x91\xe3\x43\xf2\xe9\xfe\xf1\xee\xf9\xb7\xa7\x07\x28\x7d\xa5\x36\x41\xd2\xbe\x40\x09\x5d\xa4\x0c\x35\x6b\x0d\x28\xf2\x4d\x00\xf4\x24\x15\x7a\x01\x59\x29\xac\x43\x9f\xb2\xc6\xef\xa2\x6b\x36\x3e\x2a\xbd\xaf\x23\x7c\x69\xe4\x21\x65\xbf\x46\xbf\xdc\x46\x77\xa6\xaa\x85\x97\x5b\x85\x0c\x32\xa3\x3d\x6a\xe2\xfd\xfc\x90\x62\x5e\xe0\xbf\x98\x5a\x54\x98\xb2\x83\xc4\x63\x6d\xac\x1f\x81\x8f\x32\xf7\x65\x9a\xe3\x41\x66\x18\x75\x9b\x25\x48\x2d\xbd\x14\x2a\x72\x99\x50\x98\xae\x07\xa1\x4f\x51\x04\xcf\x25\x82\xd8\x9a\x03\xc2\x17\xe8\x84\xbd\x28\x1c\x2c\xaa\xc6\xf9\x05\x89\x56\x08\x3b\x69\x9d\x27\x09\xf0\x04\x6d\x73\xbb\x01\xa1\x4f\x60\x68\x6b\xbb\xfd\xe0\x1b\x5a\x52\xcf\x59\x88\x9d\x47\xbb\x68\x29\x0e\x7b\xc9\x28\x7a\x1f\x7e\x8e\x2e\xb3\xb2\xf6\xd2\xe8\x51\x06\x4f\x46\x9d\x0a\xd4\xd0\x38\x74\x20\x80\x96\xe8\x65\x06\x42\x15\xc6\x4a\x5f\x56\xe0\x0d\x88\xba\xb6\xe6\x55\x56\xc2\x53\xf8\x1a\x68\x51\x20\x1c\xe9\x94\x08\xae\x12\x4a\x81\x6e\xaa\x2d\x05\x68\x76\x50\xb7\x7a\x46\x3b\xfe\x41\x01\x45\xe3\x4b\x63\x47\xce\x7f\x42\x63\x49\xea\xd6\x56\xa5\x51\xf9\xc0\xe8\xc3\x04\x67\xb3\x94\xc5\xb1\xd8\x8b\x57\x5e\x18\x53\x28\x14\xb5\x74\x9c\x52\xee\x6c\xb1\x92\x5b\x17\xef\x5f\x1a\xb4\xa7\x78\xcd\xd7\x6b\xfe\xf9\xbc\xe3\x95\xd4\x7c\xef\xd8\x26\x89\x7b\xa9\xb3\xae\x97\x5e\xe1\xe6\x9c\x70\x12\xf7\xdb\x20\x89\xfb\x16\x0a\x92\xad\xc9\x4f\xed\xbb\x5c\x5f\x40\xb4\x0e\xc8\x24\xab\x02\x64\x9e\x32\x8b\xbb\x3f\xbb\xf4\xd9\x39\x3c\x32\xb0\xcd\x19\x90\x29\xe1\x5c\xca\x6a\x8b\x6d\xa7\x10\xae\x18\x50\x1d\x25\x5e\xfd\x90\x89\xac\xc4\x2d\xfd\x71\xe9\x8f\x8f\x8f\x6d\xf7\xfe\x1f\xda\x7a\x1a\xed\xf3\x34\xda\x97\x69\xb4\xaf\xa7\xd1\xbe\x99\x46\xfb\x76\x1a\xed\xbb\x69\xb4\xeb\x69\xb4\xef\xdf\xd1\x82\xa1\xb5\xfd\xa9\x6e\xaf\x43\x5d\x2b\x99\x89\xf6\x42\xc6\x7b\x71\x10\xfd\xe1\xf9\x16\xec\x1a\x9d\xb5\x27\x90\x1b\x6a\x46\x15\xce\xff\xea\xcc\xed\x73\x10\x16\x72\x48\x41\xe3\x11\xee\xe9\x4e\x86\x73\x5e\xa0\x7f\x96\x15\xad\x6e\x82\x7f\x60\x57\xe1\x8c\x62\xfa\xfd\x83\x30\xff\x98\xcd\x39\x52\x6c\xe1\xe0\x24\x94\x3a\xc7\xd7\xa5\xf4\x58\x8d\xfc\x0c\xbe\x28\x29\xf2\x76\x15\x76\xc7\x5c\x78\x6f\xc3\x19\xd9\x66\xe4\x6c\x0c\xed\x61\xf4\xcb\x2d\xd6\x4a\x64\x18\xc6\x97\x02\xf0\xc5\x55\x5c\xc8\x25\xb0\x8b\x89\xc1\x57\x90\xff\x47\xe3\xbd\x93\x65\xab\x38\x42\xbd\x8d\x53\x74\x7d\xda\xa6\xf1\x61\x5f\xa6\x25\xac\x57\xab\xd5\x08\x4e\xa3\xc6\x19\x85\x5c\x99\x22\x64\x4d\x9d\x53\xb9\xf2\x7e\x80\x71\xce\xd9\xbc\xc3\xbd\xf5\x82\x43\xa1\x6f\x82\xcb\xdc\x48\xe2\x7e\x28\xd0\x20\xe8\xbe\x3f\x7f\x07\x00\x00\xff\xff\xc7\xbf\x2b\x59\x90\x06\x00\x00")
+var _templatesIndexHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x9d\x55\x61\x6f\xdb\x36\x10\xfd\xee\x5f\x71\xe5\x32\x44\x76\x2d\x2a\x4e\xbb\xad\x5b\xac\x14\x5d\xdb\x14\x1d\xb0\x26\x9b\x33\x60\xc3\x30\x0c\xb4\x74\x96\x19\x50\xa2\x42\x9e\x1c\x1b\x43\xff\xfb\x8e\x92\xe5\x38\x49\x07\x0c\xd6\x07\x9b\x7c\x7c\xef\xdd\x1d\x75\xa4\xa6\xcf\xde\x5d\xbe\xbd\xfe\xe3\xea\x3d\x2c\xa9\x34\xe7\x83\x69\xf8\x03\xa3\xaa\x22\x15\x58\x89\x00\xa0\xca\xcf\x07\xc0\xcf\xb4\x44\x52\x90\x2d\x95\xf3\x48\xa9\x68\x68\x11\xbf\x12\xfb\x4b\x4b\xa2\x3a\xc6\xdb\x46\xaf\x52\xf1\x7b\xfc\xdb\x9b\xf8\xad\x2d\x6b\x45\x7a\x6e\x50\x40\x66\x2b\xc2\x8a\x75\x1f\xdf\xa7\x98\x17\xf8\x40\x59\xa9\x12\x53\xb1\xd2\x78\x57\x5b\x47\x7b\xe4\x3b\x9d\xd3\x32\xcd\x71\xa5\x33\x8c\xdb\xc9\x18\x74\xa5\x49\x2b\x13\xfb\x4c\x19\x4c\x27\xbd\xd1\xb3\x38\x86\xeb\x25\x82\x9a\xdb\x15\xc2\x0b\x68\x8d\x49\x15\x1e\x46\x65\xe3\x69\xc4\xa6\x25\xc2\x42\x3b\x4f\x6c\x01\xc4\xd4\x50\xdb\x19\xa8\x6a\x03\x96\xa7\xae\x9d\xf7\xb1\x21\x88\x3a\xcd\x48\x2d\x08\xdd\x28\x48\x3c\x76\x96\x71\xfc\x34\xfd\x1c\x7d\xe6\x74\x4d\xda\x56\x7b\x15\x5c\x59\xb3\x29\xb0\x82\xc6\xa3\x07\x05\x3c\x44\xd2\x19\x28\x53\x58\xa7\x69\x59\x02\x59\x50\x75\xed\xec\x5a\x97\x8a\x38\xfd\x0a\x78\x50\x20\xdc\xf1\x2a\x0b\x7c\xa9\x8c\x81\xaa\x29\xe7\x9c\xa0\x5d\x40\x1d\xfc\x6c\xe5\xe5\x17\x36\x50\x35\xb4\xb4\x6e\x2f\xf8\x07\xb4\x8e\xad\xde\xb8\x72\x69\x4d\xde\x2b\xba\x34\xc1\xbb\x2c\x15\x49\xa2\x6e\xd4\x5a\x16\xd6\x16\x06\x55\xad\xbd\xe4\x92\x5b\x2c\x31\x7a\xee\x93\x9b\xdb\x06\xdd\x26\x99\xc8\xc9\x44\x9e\x6e\x67\xb2\xd4\x95\xbc\xf1\xe2\x7c\x9a\x74\x56\x5b\x5f\xd2\x64\xf0\x7c\x5b\xf0\x34\xe9\xa6\x83\x69\xd2\xb5\xd0\x60\x3a\xb7\xf9\x26\xfc\x2f\x27\xf7\x24\x1e\x0f\x18\xaa\x41\xe7\xa9\xf0\xa4\xa8\x69\x7d\xeb\xc0\xd3\x65\xd1\xc2\x0e\x17\x7f\xb7\x7b\x22\xb6\x39\x33\x20\x7a\x42\x66\x94\xf7\xa9\xa8\x1d\x86\xf6\x61\x5e\xd1\xb3\x5a\x49\x72\xf2\x3a\x53\xd9\x12\xe7\xfc\x36\xd3\x8b\xcb\xcb\xd0\xd2\xff\x47\x36\x39\x4c\x76\x7a\x98\xec\xc5\x61\xb2\x97\x87\xc9\xbe\x39\x4c\xf6\xed\x61\xb2\xef\x0e\x93\xbd\x3a\x4c\xf6\xfd\x13\xd9\xa0\xef\x77\xda\xd4\xe1\x8c\xd4\xb5\xd1\x99\x0a\xa7\x34\xb9\x51\x2b\xd5\x2d\x6e\x8f\xc6\xa2\xa9\xb2\xb0\x02\xb9\xe5\x0e\x35\xd1\xf0\x9f\x16\x0e\xcf\x4a\x39\xc8\x21\x85\x0a\xef\xe0\x1d\x1f\xd4\x68\x28\x0b\xa4\x6b\x5d\xf2\xe8\x6c\xb0\xa3\x1d\x45\xc7\x9c\xd3\x9f\x5f\x48\xf3\xaf\xe3\xa1\x44\xce\x2d\xea\x83\x44\xba\xca\x71\x3d\xd6\x84\xe5\x5e\x9c\x3e\x16\x17\xc5\xd1\x8e\xa2\x76\x59\x2a\x22\x17\x1d\x33\x76\xcc\xc1\xf6\xa9\x1d\x8d\x7f\xa5\xc3\xda\xa8\x0c\xa3\xe4\x7e\x03\xe4\xe8\x28\x29\xf4\x18\xc4\x3d\x24\xe0\x39\xe4\x8f\x3c\x9e\x06\x19\x07\xc7\x3d\xd6\xe7\x07\x25\x86\xc2\x7f\x9a\x5d\x7e\x8a\x44\xb2\x3d\xb1\xe3\xdd\xce\x45\x1d\x32\x84\xa7\x15\xdd\x86\x44\xdb\x55\xf9\x4b\xa3\x8c\xa6\xcd\xe3\x34\xc4\x57\x5b\xbf\xa1\x24\x5c\x53\x24\xc2\x6d\xe9\xda\x77\xf5\x03\x84\xcc\xb7\xf2\x0f\x3b\x98\xb1\x10\x5c\x53\x85\xde\x3f\xe0\x5c\x74\x18\x3c\x7f\x10\x23\x3c\x2c\xf0\xba\xd4\x46\xf1\xfd\xbb\xe9\x34\xb7\x72\xb6\x43\x24\xd9\x0b\xbd\xc6\x3c\x3a\x1d\x06\xf7\xaf\xc7\x70\x35\xfb\xf4\x6b\xcf\x0b\xe3\x47\x0c\xc8\x7f\x1c\xc3\x6c\xf6\xf1\xe7\x9d\x17\x8f\x77\x9c\x97\xc3\xff\xda\x47\xdf\xb5\x8f\x6d\x28\xea\xda\x6d\x0c\x93\x93\x93\x93\x3d\x3a\xdf\xe3\xde\x1a\x94\xc6\x16\x91\x68\xea\x9c\xdb\x2e\xef\xbe\x0e\x52\x4a\x31\x6c\x79\x9f\x3b\xc3\xbe\x61\xcf\x06\xf7\x97\xf2\x34\xe9\x6e\x5c\xbe\x65\xdb\x8f\xfb\xbf\xf6\x5a\x70\x21\xed\x07\x00\x00")
 
 func templatesIndexHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -83,7 +83,7 @@ func templatesIndexHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "templates/index.html", size: 1680, mode: os.FileMode(420), modTime: time.Unix(1460808963, 0)}
+	info := bindataFileInfo{name: "templates/index.html", size: 2029, mode: os.FileMode(420), modTime: time.Unix(1792420926, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
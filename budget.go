@@ -0,0 +1,294 @@
+package polygen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"log"
+	"math"
+	"math/bits"
+	"math/rand"
+)
+
+// Quantization sets the precision of the .poly encoding (see EncodePolyQuantized). The zero value encodes at full
+// precision.
+type Quantization struct {
+	// CoordBits, if > 0 and fewer than the image size needs, snaps each coordinate to one of 2^CoordBits evenly
+	// spaced positions across the image.
+	CoordBits int
+
+	// ColorBits, if between 1 and 7, snaps each non-premultiplied color channel to one of 2^ColorBits evenly
+	// spaced values.
+	ColorBits int
+}
+
+// coordBits returns the number of bits used for coordinates along an axis of the given size.
+func (q Quantization) coordBits(size int) int {
+	full := bits.Len(uint(size - 1))
+	if q.CoordBits <= 0 || q.CoordBits >= full {
+		return full
+	}
+
+	return q.CoordBits
+}
+
+// colorBits returns the number of bits used for each color channel.
+func (q Quantization) colorBits() int {
+	if q.ColorBits <= 0 || q.ColorBits >= 8 {
+		return 8
+	}
+
+	return q.ColorBits
+}
+
+// quantizeCoord returns the value stored for coordinate v along an axis of the given size, in n bits.
+func quantizeCoord(v, size, n int) int {
+	if n >= bits.Len(uint(size-1)) {
+		return v
+	}
+
+	levels := 1<<uint(n) - 1
+	return int(math.Round(float64(v) * float64(levels) / float64(size-1)))
+}
+
+// expandCoord is the inverse of quantizeCoord.
+func expandCoord(stored, size, n int) int {
+	if n >= bits.Len(uint(size-1)) {
+		return stored
+	}
+
+	levels := 1<<uint(n) - 1
+	return int(math.Round(float64(stored) * float64(size-1) / float64(levels)))
+}
+
+// mutateOnGrid is like mutateNearby, but for a point on the coordinate grid of q: it moves the point to a grid
+// position within PointMutationMaxDistance pixels, or to an adjacent one if the grid is coarser than that, so that
+// the move survives quantization.
+func (p *Point) mutateOnGrid(maxW, maxH int, q Quantization) {
+	p.X = nearbyGridCoord(p.X, maxW, q.coordBits(maxW))
+	p.Y = nearbyGridCoord(p.Y, maxH, q.coordBits(maxH))
+}
+
+// nearbyGridCoord returns a random grid position near v, along an axis of the given size with n bits, see
+// mutateOnGrid.
+func nearbyGridCoord(v, size, n int) int {
+	if size <= 1 {
+		return v
+	}
+
+	levels := size - 1
+	if n < bits.Len(uint(size-1)) {
+		levels = 1<<uint(n) - 1
+	}
+
+	maxSteps := PointMutationMaxDistance * levels / (size - 1)
+	if maxSteps < 1 {
+		maxSteps = 1
+	}
+
+	delta := rand.Intn(maxSteps + 1)
+	if RandomBool() {
+		delta = -delta
+	}
+
+	return expandCoord(clampInt(quantizeCoord(v, size, n)+delta, 0, levels), size, n)
+}
+
+// quantizeChannel returns the value stored for the 8-bit channel v in n bits.
+func quantizeChannel(v uint8, n int) uint8 {
+	levels := 1<<uint(n) - 1
+	return uint8(math.Round(float64(v) * float64(levels) / 255))
+}
+
+// expandChannel is the inverse of quantizeChannel.
+func expandChannel(stored uint8, n int) uint8 {
+	levels := 1<<uint(n) - 1
+	return uint8(math.Round(float64(stored) * 255 / float64(levels)))
+}
+
+// quantizeColor returns the values stored for the non-premultiplied R, G, B and A of c in n bits each. Colors that
+// are already quantized (i.e. that came from expandColor) are returned unchanged, even though translucent colors
+// may have several quantized values that premultiply to the same color.
+func quantizeColor(c color.Color, n int) [4]uint8 {
+	straight := straightColor(c)
+	if n >= 8 {
+		return [4]uint8{straight.R, straight.G, straight.B, straight.A}
+	}
+
+	alpha := quantizeChannel(straight.A, n)
+	a := expandChannel(alpha, n)
+	target := color.RGBAModel.Convert(c).(color.RGBA)
+
+	channel := func(v, want uint8) uint8 {
+		result := quantizeChannel(v, n)
+		if target.A != a || premultiply(expandChannel(result, n), a) == want {
+			return result
+		}
+
+		// find the closest stored value that premultiplies to c
+		best, bestDist := result, 256
+		for q := 0; q < 1<<uint(n); q++ {
+			if dist := int(math.Abs(float64(q - int(result)))); premultiply(expandChannel(uint8(q), n), a) == want && dist < bestDist {
+				best, bestDist = uint8(q), dist
+			}
+		}
+
+		return best
+	}
+
+	return [4]uint8{channel(straight.R, target.R), channel(straight.G, target.G), channel(straight.B, target.B), alpha}
+}
+
+// expandColor returns the color for the values stored by quantizeColor.
+func expandColor(stored [4]uint8, n int) color.Color {
+	if n < 8 {
+		for i := range stored {
+			stored[i] = expandChannel(stored[i], n)
+		}
+	}
+
+	return color.RGBAModel.Convert(color.NRGBA{stored[0], stored[1], stored[2], stored[3]})
+}
+
+// quantize snaps the Candidate's points and colors to the precision of q, so that it renders exactly as it will
+// after a round trip through the .poly format. Returns the region of the rendered image that may have changed,
+// and the index of the lowest polygon that changed (or len(Polygons), if none did).
+func (c *Candidate) quantize(q Quantization) (image.Rectangle, int) {
+	dirty := image.Rectangle{}
+	lowest := len(c.Polygons)
+
+	xBits, yBits, colorBits := q.coordBits(c.W), q.coordBits(c.H), q.colorBits()
+
+	for i, poly := range c.Polygons {
+		changed := false
+		before := poly.bounds()
+
+		for j, p := range poly.Points {
+			snapped := Point{
+				expandCoord(quantizeCoord(p.X, c.W, xBits), c.W, xBits),
+				expandCoord(quantizeCoord(p.Y, c.H, yBits), c.H, yBits),
+			}
+			if snapped != p {
+				poly.Points[j] = snapped
+				changed = true
+			}
+		}
+
+		if col := expandColor(quantizeColor(poly.Color, colorBits), colorBits); col != color.RGBAModel.Convert(poly.Color) {
+			poly.Color = col
+			changed = true
+		}
+
+		if changed {
+			dirty = dirty.Union(before.Union(poly.bounds()))
+			if i < lowest {
+				lowest = i
+			}
+		}
+	}
+
+	if lowest < len(c.Polygons) {
+		c.layers = nil
+	}
+
+	return dirty.Intersect(c.bounds()), lowest
+}
+
+// jpegAtSize encodes img as the best quality JPEG that fits in size bytes, for comparison with a .poly image of
+// the same size. Returns the JPEG quality setting, the actual size, and the decoded image. If even the lowest
+// quality is too large, the quality is 0, and the size is that of the lowest quality JPEG.
+func jpegAtSize(img image.Image, size int) (quality, actual int, decoded image.Image, err error) {
+	var best []byte
+	smallest := 0
+
+	// the encoded size grows with quality, so binary search for the largest quality that fits
+	lo, hi := 1, 100
+	for lo <= hi {
+		mid := (lo + hi) / 2
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: mid}); err != nil {
+			return 0, 0, nil, err
+		}
+
+		if smallest == 0 || buf.Len() < smallest {
+			smallest = buf.Len()
+		}
+
+		if buf.Len() <= size {
+			quality, best = mid, buf.Bytes()
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	if best == nil {
+		return 0, smallest, nil, nil
+	}
+
+	decoded, err = jpeg.Decode(bytes.NewReader(best))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	return quality, len(best), decoded, nil
+}
+
+// withinBudget reports whether c fits in Options.MaxBytes.
+func (e *Evolver) withinBudget(c *Candidate) bool {
+	size, err := PolySize(c, e.options.Quantization)
+	return err == nil && size <= e.options.MaxBytes
+}
+
+// fitBudget snaps the most fit candidate to Options.Quantization, and removes polygons from the top until it fits
+// in Options.MaxBytes.
+func (e *Evolver) fitBudget() {
+	c := e.mostFit.copyOf()
+	c.grid = e.options.Quantization
+	c.quantize(e.options.Quantization)
+
+	for len(c.Polygons) > 1 && !e.withinBudget(c) {
+		c.Polygons = c.Polygons[:len(c.Polygons)-1]
+	}
+
+	size, err := PolySize(c, e.options.Quantization)
+	if err != nil {
+		log.Fatalf("error encoding candidate: %s", err)
+	}
+	if size > e.options.MaxBytes {
+		log.Fatalf("a budget of %d bytes is too small, a single polygon takes %d", e.options.MaxBytes, size)
+	}
+
+	if !e.options.Quiet {
+		log.Printf("starting with %d polygons in %d bytes, budget: %d bytes", len(c.Polygons), size, e.options.MaxBytes)
+	}
+
+	e.mostFit = c
+	e.candidates[0] = c
+}
+
+// reportBudget logs the encoded size of the most fit candidate, and compares it with a JPEG of the same size.
+func (e *Evolver) reportBudget() {
+	size, err := PolySize(e.mostFit, e.options.Quantization)
+	if err != nil {
+		log.Fatalf("error encoding candidate: %s", err)
+	}
+	log.Printf("encoded size: %d bytes of %d, %d polygons, %d vertices", size, e.options.MaxBytes, len(e.mostFit.Polygons), e.mostFit.vertexCount())
+
+	quality, actual, img, err := jpegAtSize(e.fullRefImgRGBA, size)
+	if err != nil {
+		log.Fatalf("error encoding JPEG: %s", err)
+	}
+	if quality == 0 {
+		log.Printf("no JPEG of the reference image fits in %d bytes, the smallest takes %d", size, actual)
+		return
+	}
+
+	jpegQuality, err := MeasureQuality(e.fullRefImgRGBA, ConvertToRGBA(img))
+	if err != nil {
+		log.Fatalf("error measuring quality: %s", err)
+	}
+	log.Printf("a %d byte JPEG (quality %d) of the reference image has %s", actual, quality, jpegQuality)
+}
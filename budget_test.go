@@ -0,0 +1,150 @@
+package polygen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestQuantizeMatchesPoly(t *testing.T) {
+	for _, q := range []Quantization{{}, {ColorBits: 5}, {CoordBits: 6, ColorBits: 4}, {CoordBits: 3, ColorBits: 1}} {
+		c := randomCandidate(90, 70, 20)
+		c.quantize(q)
+
+		// a quantized candidate is unchanged by a round trip through the .poly format, or by quantizing again
+		var buf bytes.Buffer
+		if err := EncodePolyQuantized(&buf, c, q); err != nil {
+			t.Fatal(err)
+		}
+
+		size, err := PolySize(c, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != buf.Len() {
+			t.Errorf("%+v: PolySize is %d, but encoded to %d bytes", q, size, buf.Len())
+		}
+
+		decoded, err := DecodePoly(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded.Image().Pix, c.Image().Pix) {
+			t.Errorf("%+v: decoded image differs from the quantized candidate", q)
+		}
+
+		if dirty, changed := c.quantize(q); changed != len(c.Polygons) || !dirty.Empty() {
+			t.Errorf("%+v: quantizing again changed polygon %d in %v", q, changed, dirty)
+		}
+	}
+}
+
+func TestQuantizeChannels(t *testing.T) {
+	for n := 1; n <= 7; n++ {
+		for v := 0; v < 256; v++ {
+			stored := quantizeChannel(uint8(v), n)
+			if expanded := expandChannel(stored, n); quantizeChannel(expanded, n) != stored {
+				t.Fatalf("%d bits: %d quantizes to %d, expands to %d, which does not quantize back", n, v, stored, expanded)
+			}
+		}
+	}
+
+	for size := 2; size < 300; size += 7 {
+		for n := 1; n < 9; n++ {
+			for v := 0; v < size; v++ {
+				x := expandCoord(quantizeCoord(v, size, n), size, n)
+				if x < 0 || x >= size || expandCoord(quantizeCoord(x, size, n), size, n) != x {
+					t.Fatalf("size %d, %d bits: %d snaps to %d, which is not stable", size, n, v, x)
+				}
+			}
+		}
+	}
+}
+
+func TestMutateOnGrid(t *testing.T) {
+	// 3 bits across 200 pixels is a grid spacing of about 28, far more than PointMutationMaxDistance
+	q := Quantization{CoordBits: 3}
+	moved := 0
+
+	for i := 0; i < 200; i++ {
+		p := Point{expandCoord(rand.Intn(8), 200, 3), expandCoord(rand.Intn(8), 100, 3)}
+		before := p
+		p.mutateOnGrid(200, 100, q)
+
+		if snapped := (Point{expandCoord(quantizeCoord(p.X, 200, 3), 200, 3), expandCoord(quantizeCoord(p.Y, 100, 3), 100, 3)}); snapped != p {
+			t.Fatalf("%v mutated to %v, which is off the grid", before, p)
+		}
+		if dx, dy := quantizeCoord(p.X, 200, 3)-quantizeCoord(before.X, 200, 3), quantizeCoord(p.Y, 100, 3)-quantizeCoord(before.Y, 100, 3); dx < -1 || dx > 1 || dy < -1 || dy > 1 {
+			t.Fatalf("%v mutated to %v, more than one grid step away", before, p)
+		}
+		if p != before {
+			moved++
+		}
+	}
+
+	if moved < 100 {
+		t.Errorf("expected most mutations to move the point, only %d of 200 did", moved)
+	}
+}
+
+func TestByteBudget(t *testing.T) {
+	ref := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			ref.Set(x, y, color.RGBA{uint8(x * 6), uint8(y * 8), uint8(rand.Intn(40)), 255})
+		}
+	}
+
+	options := Options{Quiet: true, MaxBytes: 120, Quantization: Quantization{CoordBits: 5, ColorBits: 5}}
+	e, err := NewEvolver(ref, "", "", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Run(300, 30, nil)
+
+	size, err := PolySize(e.mostFit, options.Quantization)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size > options.MaxBytes {
+		t.Errorf("expected at most %d bytes, got: %d", options.MaxBytes, size)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodePolyQuantized(&buf, e.mostFit, options.Quantization); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodePoly(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Image().Pix, e.mostFit.img.Pix) {
+		t.Errorf("the decoded .poly image differs from the evolved one")
+	}
+
+	if _, err := NewEvolver(ref, "", "", Options{MaxBytes: 100, Levels: []Level{{Scale: 0.5, Generations: 10}}}); err == nil {
+		t.Errorf("expected error for a byte budget with levels")
+	}
+	if _, err := NewEvolver(ref, "", "", Options{MaxBytes: 100, PolishAfter: 50}); err == nil {
+		t.Errorf("expected error for a byte budget with polishing")
+	}
+}
+
+func TestJPEGAtSize(t *testing.T) {
+	img := randomCandidate(64, 64, 20).Image()
+
+	quality, actual, decoded, err := jpegAtSize(img, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quality == 0 || actual > 2000 || decoded.Bounds() != img.Bounds() {
+		t.Errorf("expected a JPEG of at most 2000 bytes, got quality %d, %d bytes", quality, actual)
+	}
+
+	if quality, actual, _, _ := jpegAtSize(img, 10); quality != 0 || actual <= 10 {
+		t.Errorf("expected no JPEG to fit in 10 bytes, got quality %d, %d bytes", quality, actual)
+	}
+}
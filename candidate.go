@@ -2,11 +2,14 @@ package polygen
 
 import (
 	"encoding/gob"
+	"fmt"
 	"image"
 	"image/color"
 	"log"
+	"math"
 	"math/rand"
 
+	"github.com/golang/freetype/raster"
 	"github.com/llgcode/draw2d/draw2dimg"
 )
 
@@ -16,6 +19,10 @@ const (
 	MutationPoint            = iota
 	MutationZOrder           = iota
 	MutationAddOrDeletePoint = iota
+
+	// MutationAddOrDeletePolygon changes the number of polygons, so it is only used when that is not fixed, see
+	// Options.MaxBytes.
+	MutationAddOrDeletePolygon = iota
 )
 
 const (
@@ -37,10 +44,18 @@ func init() {
 
 // Candidate is a potential solution (set of polygons) to the problem of how to best represent the reference image.
 type Candidate struct {
-	W, H     int
-	Polygons []*Polygon
-	img      *image.RGBA // candidate this image for evaluation
-	Fitness  uint64
+	W, H        int
+	Polygons    []*Polygon
+	img         *image.RGBA   // candidate this image for evaluation
+	linear      *image.RGBA64 // the linear light render, if Linear
+	layers      []*image.RGBA // cached partial renders, see renderLayers
+	Fitness     uint64
+	rawFitness  uint64       // the unnormalized error behind Fitness, see evaluateChange
+	Transparent bool         // if true, polygons are drawn on a transparent canvas rather than a black one
+	Linear      bool         // if true, polygons are composited in linear light, see renderLinear
+	renderer    Renderer     // defaults to Draw2D
+	grid        Quantization // if CoordBits is set, points are mutated in steps of its coordinate grid
+	rejected    bool         // if true, evaluation was aborted early because the candidate is worse than its parent
 }
 
 // Polygon is a set of points with a given fill color.
@@ -91,7 +106,7 @@ func randomPoint(maxW, maxH int) Point {
 
 // Copies the Candidate, minus the img (we assume the copy will be mutated/rendered after).
 func (c *Candidate) copyOf() *Candidate {
-	result := &Candidate{W: c.W, H: c.H}
+	result := &Candidate{W: c.W, H: c.H, Transparent: c.Transparent, Linear: c.Linear, renderer: c.renderer, grid: c.grid}
 	for i := 0; i < len(c.Polygons); i++ {
 		result.Polygons = append(result.Polygons, c.Polygons[i].copyOf())
 	}
@@ -99,12 +114,42 @@ func (c *Candidate) copyOf() *Candidate {
 	return result
 }
 
+// scaledTo returns a copy of the Candidate with its points rescaled to fit a w x h canvas.
+func (c *Candidate) scaledTo(w, h int) *Candidate {
+	result := c.copyOf()
+	result.W, result.H = w, h
+
+	if w == c.W && h == c.H {
+		return result
+	}
+
+	sx := float64(w) / float64(c.W)
+	sy := float64(h) / float64(c.H)
+
+	for _, poly := range result.Polygons {
+		for i := range poly.Points {
+			poly.Points[i] = poly.Points[i].scaled(sx, sy, w, h)
+		}
+	}
+
+	return result
+}
+
 // mutateInPlace chooses a random polygon from the candidate and makes a random mutation to it.
 func (c *Candidate) mutateInPlace() {
+	c.mutate(Mutations)
+}
+
+// mutate chooses a random polygon from the candidate and makes a random mutation to it, chosen from mutations.
+// Returns the region of the rendered image that may have changed as a result, and the index of the lowest
+// polygon that changed.
+func (c *Candidate) mutate(mutations []int) (image.Rectangle, int) {
 	locus := rand.Intn(len(c.Polygons))
 	poly := c.Polygons[locus]
+	dirty := poly.bounds()
+	c.layers = nil
 
-	switch randomMutation() {
+	switch mutations[rand.Intn(len(mutations))] {
 	case MutationColor:
 		poly.Color = mutateColor(poly.Color)
 
@@ -113,16 +158,21 @@ func (c *Candidate) mutateInPlace() {
 
 	case MutationPoint:
 		pointIndex := rand.Intn(len(poly.Points))
-		poly.Points[pointIndex].mutateNearby(c.W, c.H)
+		if c.grid.CoordBits > 0 {
+			poly.Points[pointIndex].mutateOnGrid(c.W, c.H, c.grid)
+		} else {
+			poly.Points[pointIndex].mutateNearby(c.W, c.H)
+		}
 
 	case MutationZOrder:
 		shufflePolygonZOrder(c.Polygons)
+		return c.bounds(), 0
 
 	case MutationAddOrDeletePoint:
 		if len(poly.Points) == MinPolygonPoints {
 			// can't delete
 			poly.addPoint(randomPoint(c.W, c.H))
-		} else if len(poly.Points) == MaxPolygonPoints {
+		} else if len(poly.Points) >= MaxPolygonPoints {
 			// can't add
 			poly.deleteRandomPoint()
 		} else {
@@ -134,9 +184,54 @@ func (c *Candidate) mutateInPlace() {
 			}
 		}
 
+	case MutationAddOrDeletePolygon:
+		stride := c.layerStride()
+
+		if len(c.Polygons) > 1 && RandomBool() {
+			c.Polygons = append(c.Polygons[:locus], c.Polygons[locus+1:]...)
+		} else {
+			poly = randomPolygon(c.W, c.H)
+			dirty = poly.bounds()
+			c.Polygons = append(c.Polygons[:locus], append([]*Polygon{poly}, c.Polygons[locus:]...)...)
+		}
+
+		// cached layers are only valid for the same stride
+		if c.layerStride() != stride {
+			locus = 0
+		}
+
 	default:
 		log.Fatal("fell through")
 	}
+
+	return dirty.Union(poly.bounds()).Intersect(c.bounds()), locus
+}
+
+// bounds returns the bounds of the Candidate's rendered image.
+func (c *Candidate) bounds() image.Rectangle {
+	return image.Rect(0, 0, c.W, c.H)
+}
+
+// bounds returns the rectangle of pixels that the polygon may affect when rendered. It extends a pixel
+// beyond the points, since edges are antialiased.
+func (p *Polygon) bounds() image.Rectangle {
+	min, max := p.Points[0], p.Points[0]
+	for _, point := range p.Points[1:] {
+		if point.X < min.X {
+			min.X = point.X
+		}
+		if point.Y < min.Y {
+			min.Y = point.Y
+		}
+		if point.X > max.X {
+			max.X = point.X
+		}
+		if point.Y > max.Y {
+			max.Y = point.Y
+		}
+	}
+
+	return image.Rect(min.X-1, min.Y-1, max.X+2, max.Y+2)
 }
 
 func (p *Polygon) addPoint(point Point) {
@@ -181,6 +276,21 @@ func (p *Point) mutateNearby(maxW, maxH int) {
 	p.Y = y
 }
 
+// scaled returns the point with its coordinates multiplied by sx and sy, clamped to a maxW x maxH canvas.
+func (p Point) scaled(sx, sy float64, maxW, maxH int) Point {
+	x := int(math.Round(float64(p.X) * sx))
+	y := int(math.Round(float64(p.Y) * sy))
+
+	if x >= maxW {
+		x = maxW - 1
+	}
+	if y >= maxH {
+		y = maxH - 1
+	}
+
+	return Point{x, y}
+}
+
 // randomColor returns a color with completely random values for RGBA.
 func randomColor() color.Color {
 	// start with non-premultiplied RGBA
@@ -220,40 +330,85 @@ func mutateAlpha(c color.Color) color.Color {
 	return color.RGBAModel.Convert(nrgba)
 }
 
-func randomMutation() int {
-	return Mutations[rand.Intn(len(Mutations))]
+func (cd *Candidate) renderImage() {
+	if cd.Linear {
+		cd.renderLinear()
+		return
+	}
+
+	cd.img = image.NewRGBA(cd.bounds())
+	cd.paint(cd.img, image.Point{}, cd.img.Bounds(), 0, len(cd.Polygons))
 }
 
-func (cd *Candidate) renderImage() {
-	cd.img = image.NewRGBA(image.Rect(0, 0, cd.W, cd.H))
-	gc := draw2dimg.NewGraphicContext(cd.img)
+// paint draws polygons [from, to) of the Candidate into dst, translated so that origin lands on the top left
+// corner of dst. The background is drawn first if from is 0. Polygons that do not overlap within are skipped.
+func (cd *Candidate) paint(dst *image.RGBA, origin image.Point, within image.Rectangle, from, to int) {
+	canvas := cd.Renderer().NewCanvas(dst, raster.NewRGBAPainter(dst))
+	cd.draw(canvas, origin, within, from, to)
+}
 
-	// paint the whole thing black to start
-	gc.SetFillColor(color.Black)
-	gc.MoveTo(0, 0)
-	gc.LineTo(float64(cd.W-1), 0)
-	gc.LineTo(float64(cd.W-1), float64(cd.H-1))
-	gc.LineTo(0, float64(cd.H-1))
-	gc.Close()
-	gc.Fill()
+// draw draws polygons [from, to) of the Candidate onto canvas, as described for paint.
+func (cd *Candidate) draw(canvas Canvas, origin image.Point, within image.Rectangle, from, to int) {
+	// paint the whole thing black to start, unless we want a transparent background
+	if from == 0 && !cd.Transparent {
+		background := []Point{{0, 0}, {cd.W - 1, 0}, {cd.W - 1, cd.H - 1}, {0, cd.H - 1}}
+		canvas.Fill(background, origin, color.Black)
+	}
 
-	gc.SetLineWidth(1)
+	for _, polygon := range cd.Polygons[from:to] {
+		if !polygon.bounds().Overlaps(within) {
+			continue
+		}
 
-	for _, polygon := range cd.Polygons {
-		gc.SetStrokeColor(polygon.Color)
-		gc.SetFillColor(polygon.Color)
+		canvas.Fill(polygon.Points, origin, polygon.Color)
+	}
+}
 
-		firstPoint := polygon.Points[0]
-		gc.MoveTo(float64(firstPoint.X), float64(firstPoint.Y))
+// Renderer returns the Renderer used to draw the Candidate, which defaults to Draw2D.
+func (cd *Candidate) Renderer() Renderer {
+	if cd.renderer == nil {
+		return Draw2D{}
+	}
 
-		for _, point := range polygon.Points[1:] {
-			gc.LineTo(float64(point.X), float64(point.Y))
-		}
+	return cd.renderer
+}
+
+// SetRenderer sets the Renderer used to draw the Candidate, and copies made from it.
+func (cd *Candidate) SetRenderer(r Renderer) {
+	cd.renderer = r
+}
+
+// Image returns the rendered image of the Candidate, rendering it first if necessary.
+func (cd *Candidate) Image() *image.RGBA {
+	if cd.img == nil {
+		cd.renderImage()
+	}
 
-		gc.Close()
-		//gc.FillStroke()
-		gc.Fill()
+	return cd.img
+}
+
+// RenderScaled renders the Candidate at scale times its own size, e.g. to make a large print of a genome that was
+// evolved against a small reference image. Since the polygons are vectors, the result is as crisp as a render at
+// the original size.
+func (cd *Candidate) RenderScaled(scale float64) (*image.RGBA, error) {
+	if scale <= 0 {
+		return nil, fmt.Errorf("invalid scale: %g", scale)
+	}
+
+	w, h := scaledSize(cd.W, cd.H, scale)
+	result := cd.scaledTo(w, h)
+	result.renderImage()
+
+	return result.img, nil
+}
+
+// RenderWidth renders the Candidate scaled to the given width, keeping its aspect ratio. See RenderScaled.
+func (cd *Candidate) RenderWidth(width int) (*image.RGBA, error) {
+	if width <= 0 {
+		return nil, fmt.Errorf("invalid width: %d", width)
 	}
+
+	return cd.RenderScaled(float64(width) / float64(cd.W))
 }
 
 func (cd *Candidate) drawAndSave(destFile string) error {
@@ -1,6 +1,8 @@
 package polygen
 
 import (
+	"image"
+	"image/color"
 	"reflect"
 	"testing"
 )
@@ -21,6 +23,57 @@ func BenchmarkRenderImage(b *testing.B) {
 	}
 }
 
+func BenchmarkRenderImageScanline(b *testing.B) {
+	c := randomCandidate(200, 200, 50)
+	c.renderer = Scanline{}
+
+	for i := 0; i < b.N; i++ {
+		c.renderImage()
+	}
+}
+
+func BenchmarkRenderImageVector(b *testing.B) {
+	c := randomCandidate(200, 200, 50)
+	c.renderer = Vector{}
+
+	for i := 0; i < b.N; i++ {
+		c.renderImage()
+	}
+}
+
+// benchmarkRenderMutated renders mutated copies of a parent, either in full or (if layered) from the parent's
+// cached layers. The whole image is redrawn either way, to measure the layers alone (see renderChange).
+func benchmarkRenderMutated(b *testing.B, polyCount int, layered bool) {
+	parent := randomCandidate(200, 200, polyCount)
+	parent.renderLayers()
+
+	mutations := []int{MutationColor, MutationPoint, MutationAlpha, MutationAddOrDeletePoint}
+	children := make([]*Candidate, 100)
+	layers := make([]int, len(children))
+	for i := range children {
+		children[i] = parent.copyOf()
+		_, layers[i] = children[i].mutate(mutations)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		j := i % len(children)
+		if layered {
+			children[j].renderChange(parent, parent.bounds(), layers[j])
+		} else {
+			children[j].renderImage()
+		}
+	}
+}
+
+func BenchmarkRenderImage50(b *testing.B)   { benchmarkRenderMutated(b, 50, false) }
+func BenchmarkRenderImage100(b *testing.B)  { benchmarkRenderMutated(b, 100, false) }
+func BenchmarkRenderImage500(b *testing.B)  { benchmarkRenderMutated(b, 500, false) }
+func BenchmarkRenderLayers50(b *testing.B)  { benchmarkRenderMutated(b, 50, true) }
+func BenchmarkRenderLayers100(b *testing.B) { benchmarkRenderMutated(b, 100, true) }
+func BenchmarkRenderLayers500(b *testing.B) { benchmarkRenderMutated(b, 500, true) }
+
 func TestCandidateCopyOf(t *testing.T) {
 	c1 := randomCandidate(100, 100, 10)
 
@@ -51,3 +104,72 @@ func TestPolygonCopyOf(t *testing.T) {
 		t.Fatalf("p1 should have diverged from p2: %+v, %+v", p1, p2)
 	}
 }
+
+func TestRenderTransparent(t *testing.T) {
+	c := randomCandidate(20, 20, 3)
+	for _, poly := range c.Polygons {
+		poly.Color = color.RGBA{}
+	}
+
+	// a fully transparent reference image
+	ref := image.NewRGBA(image.Rect(0, 0, 20, 20))
+
+	c.Transparent = true
+	c.renderImage()
+	diff, _ := FastCompare(ref, c.img)
+	if diff != 0 {
+		t.Fatalf("expected transparent canvas to match transparent reference, got diff: %d", diff)
+	}
+
+	c.Transparent = false
+	c.renderImage()
+	diff, _ = FastCompare(ref, c.img)
+	if diff == 0 {
+		t.Fatalf("expected black canvas to differ from transparent reference")
+	}
+
+	if c2 := c.copyOf(); c2.Transparent != c.Transparent {
+		t.Fatalf("copyOf did not preserve Transparent")
+	}
+}
+
+func TestRenderScaled(t *testing.T) {
+	c := &Candidate{W: 40, H: 30, Polygons: []*Polygon{
+		{Points: []Point{{10, 10}, {20, 10}, {20, 20}, {10, 20}}, Color: color.RGBA{255, 0, 0, 255}},
+	}}
+
+	img, err := c.RenderScaled(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := image.Rect(0, 0, 160, 120); img.Bounds() != expected {
+		t.Fatalf("expected bounds %v, got: %v", expected, img.Bounds())
+	}
+
+	// edges on pixel boundaries stay sharp, rather than being blurred by upsampling
+	for _, tc := range []struct {
+		x, y int
+		c    color.RGBA
+	}{
+		{39, 39, color.RGBA{0, 0, 0, 255}},
+		{40, 40, color.RGBA{255, 0, 0, 255}},
+		{79, 79, color.RGBA{255, 0, 0, 255}},
+		{80, 80, color.RGBA{0, 0, 0, 255}},
+	} {
+		if actual := img.RGBAAt(tc.x, tc.y); actual != tc.c {
+			t.Errorf("expected %v at (%d, %d), got: %v", tc.c, tc.x, tc.y, actual)
+		}
+	}
+
+	img, err = c.RenderWidth(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := image.Rect(0, 0, 100, 75); img.Bounds() != expected {
+		t.Errorf("expected bounds %v, got: %v", expected, img.Bounds())
+	}
+
+	if _, err := c.RenderScaled(0); err == nil {
+		t.Errorf("expected error for scale 0")
+	}
+}
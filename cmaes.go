@@ -0,0 +1,142 @@
+package polygen
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// CMAES implements the separable variant of the Covariance Matrix Adaptation Evolution Strategy
+// (Ros & Hansen, "A Simple Modification in CMA-ES Achieving Linear Time and Space Complexity", 2008).
+// Only the diagonal of the covariance matrix is adapted, which keeps each generation linear in the
+// genome size; a Candidate with a few hundred polygons has thousands of genes, where the full matrix
+// (and its eigendecomposition) would be far too expensive.
+type CMAES struct {
+	Lambda int     // population size, defaults to 4 + 3 ln(n)
+	Sigma  float64 // initial step size, as a fraction of each gene's range. Defaults to 0.02
+}
+
+func (cm *CMAES) Optimize(x0, lower, upper []float64, f Objective, evals int) []float64 {
+	if evals < 1 {
+		return x0
+	}
+
+	n := len(x0)
+	nf := float64(n)
+
+	lambda := cm.Lambda
+	if lambda < 2 {
+		lambda = 4 + int(3*math.Log(nf))
+	}
+	sigma := cm.Sigma
+	if sigma == 0 {
+		sigma = 0.02
+	}
+
+	// selection weights
+	mu := lambda / 2
+	weights := make([]float64, mu)
+	sumW, sumW2 := 0.0, 0.0
+	for i := range weights {
+		weights[i] = math.Log(float64(mu)+0.5) - math.Log(float64(i+1))
+		sumW += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sumW
+		sumW2 += weights[i] * weights[i]
+	}
+	mueff := 1 / sumW2
+
+	// adaptation constants, with the learning rates scaled up for the diagonal-only model
+	cc := (4 + mueff/nf) / (nf + 4 + 2*mueff/nf)
+	cs := (mueff + 2) / (nf + mueff + 5)
+	c1 := 2 / ((nf+1.3)*(nf+1.3) + mueff)
+	cmu := math.Min(1-c1, 2*(mueff-2+1/mueff)/((nf+2)*(nf+2)+mueff))
+	c1 = math.Min(1, c1*(nf+2)/3)
+	cmu = math.Min(1-c1, cmu*(nf+2)/3)
+	damps := 1 + 2*math.Max(0, math.Sqrt((mueff-1)/(nf+1))-1) + cs
+	chiN := math.Sqrt(nf) * (1 - 1/(4*nf) + 1/(21*nf*nf))
+
+	norm := normalizer{lower, upper}
+	obj := norm.objective(f)
+
+	mean := norm.toUnit(x0)
+	diagC := make([]float64, n)
+	for i := range diagC {
+		diagC[i] = 1
+	}
+	ps := make([]float64, n)
+	pc := make([]float64, n)
+
+	best := append([]float64(nil), mean...)
+	bestScore := obj(best)
+	evals--
+
+	for gen := 0; evals >= lambda; gen++ {
+		xs := make([][]float64, lambda)
+		ys := make([][]float64, lambda)
+		for k := range xs {
+			xs[k] = make([]float64, n)
+			ys[k] = make([]float64, n)
+			for i := 0; i < n; i++ {
+				ys[k][i] = math.Sqrt(diagC[i]) * rand.NormFloat64()
+				xs[k][i] = clampUnit(mean[i] + sigma*ys[k][i])
+
+				// keep the step consistent with the clamped point that is actually evaluated
+				ys[k][i] = (xs[k][i] - mean[i]) / sigma
+			}
+		}
+
+		scores := evaluateAll(xs, obj)
+		evals -= lambda
+
+		order := make([]int, lambda)
+		for k := range order {
+			order[k] = k
+		}
+		sort.Slice(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+
+		if scores[order[0]] < bestScore {
+			bestScore = scores[order[0]]
+			best = xs[order[0]]
+		}
+
+		// weighted recombination of the mu best steps
+		yw := make([]float64, n)
+		for j := 0; j < mu; j++ {
+			y := ys[order[j]]
+			for i := range yw {
+				yw[i] += weights[j] * y[i]
+			}
+		}
+
+		psNorm := 0.0
+		for i := range mean {
+			mean[i] += sigma * yw[i]
+			ps[i] = (1-cs)*ps[i] + math.Sqrt(cs*(2-cs)*mueff)*yw[i]/math.Sqrt(diagC[i])
+			psNorm += ps[i] * ps[i]
+		}
+		psNorm = math.Sqrt(psNorm)
+
+		hsig := 0.0
+		if psNorm/math.Sqrt(1-math.Pow(1-cs, float64(2*(gen+1))))/chiN < 1.4+2/(nf+1) {
+			hsig = 1
+		}
+
+		for i := range diagC {
+			pc[i] = (1-cc)*pc[i] + hsig*math.Sqrt(cc*(2-cc)*mueff)*yw[i]
+
+			rankMu := 0.0
+			for j := 0; j < mu; j++ {
+				y := ys[order[j]][i]
+				rankMu += weights[j] * y * y
+			}
+
+			diagC[i] = (1-c1-cmu)*diagC[i] + c1*(pc[i]*pc[i]+(1-hsig)*cc*(2-cc)*diagC[i]) + cmu*rankMu
+		}
+
+		sigma *= math.Exp((cs / damps) * (psNorm/chiN - 1))
+	}
+
+	return norm.fromUnit(best)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armhold/polygen"
+)
+
+var (
+	srcDir      string
+	srcGlob     string
+	outDir      string
+	maxGen      int
+	polyCount   int
+	workers     int
+	levelsArg   string
+	verbose     bool
+	transparent bool
+	newRenderer func() (polygen.Renderer, error)
+)
+
+func init() {
+	flag.StringVar(&srcDir, "dir", "", "directory of source images to process")
+	flag.StringVar(&srcGlob, "glob", "", "glob pattern of source images to process, e.g. 'images/*.jpg'")
+	flag.StringVar(&outDir, "out", "out", "directory for output images and checkpoints")
+	flag.IntVar(&maxGen, "max", 100000, "the number of generations per image")
+	flag.IntVar(&polyCount, "poly", 50, "the number of polygons")
+	flag.IntVar(&workers, "workers", 1, "how many images to evolve concurrently")
+	flag.StringVar(&levelsArg, "levels", "", "coarse-to-fine schedule of scale:generations pairs to run before full size, e.g. 0.25:2000,0.5:2000")
+	flag.BoolVar(&transparent, "transparent", false, "evolve on a transparent canvas, to match (and preserve) the transparency of the sources")
+	newRenderer = polygen.RendererFlags(flag.CommandLine)
+	flag.BoolVar(&verbose, "v", false, "log per-generation statistics for each image")
+
+	flag.Parse()
+
+	if (srcDir == "") == (srcGlob == "") || outDir == "" || workers < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	rand.Seed(time.Now().UTC().UnixNano())
+}
+
+func main() {
+	files, err := sourceFiles()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		log.Fatal("no source images found")
+	}
+
+	// outputs are named after the source file without its extension, so e.g. a.jpg and a.png would collide
+	sources := make(map[string]string)
+	for _, file := range files {
+		name := outputName(file)
+		if other, ok := sources[name]; ok {
+			log.Fatalf("%s and %s would both be saved as %s in %s, rename one of them", other, file, name, outDir)
+		}
+		sources[name] = file
+	}
+
+	levels, err := polygen.ParseLevels(levelsArg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r, err := newRenderer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options := polygen.Options{Levels: levels, Quiet: !verbose, Transparent: transparent, Renderer: r}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				process(file, options)
+			}
+		}()
+	}
+
+	for _, file := range files {
+		jobs <- file
+	}
+	close(jobs)
+
+	wg.Wait()
+	log.Printf("batch complete: %d images", len(files))
+}
+
+// sourceFiles returns the sorted list of images named by the -dir or -glob flag.
+func sourceFiles() ([]string, error) {
+	if srcGlob != "" {
+		files, err := filepath.Glob(srcGlob)
+		sort.Strings(files)
+		return files, err
+	}
+
+	entries, err := ioutil.ReadDir(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".jpg", ".jpeg", ".png", ".gif":
+			result = append(result, filepath.Join(srcDir, entry.Name()))
+		}
+	}
+
+	return result, nil
+}
+
+// outputName returns the name of the output image and checkpoint for file, without extension.
+func outputName(file string) string {
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// process evolves a single image, resuming from its checkpoint in outDir if there is one. Images that cannot be
+// read, or whose checkpoint does not match, are logged and skipped rather than ending the batch.
+func process(file string, options polygen.Options) {
+	dst := filepath.Join(outDir, outputName(file)+".png")
+	cp := filepath.Join(outDir, polygen.DeriveCheckpointFile(file, "", polyCount))
+
+	refImg, err := polygen.ReadImage(file)
+	if err != nil {
+		log.Printf("skipping %s: %s", file, err)
+		return
+	}
+
+	if _, err := os.Stat(cp); err == nil {
+		checkpoint, err := polygen.LoadCheckpoint(cp)
+		if err != nil {
+			log.Printf("skipping %s: %s", file, err)
+			return
+		}
+
+		if checkpoint.Generation >= maxGen {
+			log.Printf("skipping %s: already complete", file)
+			return
+		}
+
+		if n := len(checkpoint.MostFit.Polygons); n != polyCount {
+			log.Printf("skipping %s: checkpoint %s has %d polygons, not %d", file, cp, n, polyCount)
+			return
+		}
+
+		log.Printf("resuming %s from generation %d", file, checkpoint.Generation)
+	} else {
+		log.Printf("starting %s", file)
+	}
+
+	evolver, err := polygen.NewEvolver(refImg, dst, cp, options)
+	if err != nil {
+		log.Printf("skipping %s: %s", file, err)
+		return
+	}
+
+	evolver.Run(maxGen, polyCount, nil)
+	log.Printf("finished %s", file)
+}
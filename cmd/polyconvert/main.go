@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/armhold/polygen"
+)
+
+var (
+	inFile    string
+	outFile   string
+	coordBits int
+	colorBits int
+)
+
+func init() {
+	flag.StringVar(&inFile, "in", "", "checkpoint to convert, either gob encoded or a JSON genome")
+	flag.StringVar(&outFile, "out", "", "output checkpoint: a JSON genome if it ends in .json, a .poly image if it ends in .poly, gob encoded otherwise")
+	flag.IntVar(&coordBits, "coordbits", 0, "for .poly output: if > 0, quantize coordinates to this many bits")
+	flag.IntVar(&colorBits, "colorbits", 0, "for .poly output: if between 1 and 7, quantize color channels to this many bits")
+
+	flag.Parse()
+
+	if inFile == "" || outFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+func main() {
+	cp, err := polygen.LoadCheckpoint(inFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if strings.EqualFold(filepath.Ext(outFile), ".poly") {
+		err = polygen.SavePoly(outFile, cp.MostFit, polygen.Quantization{CoordBits: coordBits, ColorBits: colorBits})
+	} else {
+		err = polygen.SaveCheckpoint(outFile, cp)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("converted %d polygons from %s to %s", len(cp.MostFit.Polygons), inFile, outFile)
+}
@@ -11,23 +11,65 @@ import (
 )
 
 var (
-	maxGen     int
-	polyCount  int
-	srcImgFile string
-	dstImgFile string
-	cpArg string
-	host, port string
+	maxGen      int
+	polyCount   int
+	srcImgFile  string
+	dstImgFile  string
+	cpArg       string
+	host, port  string
+	levelsArg   string
+	tileSize    int
+	overlap     int
+	optimizer   string
+	polish      int
+	recycle     int
+	weightFile  string
+	edgeWeight  float64
+	transparent bool
+	metric      string
+	sample      float64
+	sampleExact int
+	linear      bool
+	stopArg     string
+	newRenderer func() (polygen.Renderer, error)
+	svgFile     string
+	maxBytes    int
+	coordBits   int
+	colorBits   int
+	polyFile    string
 )
 
+// tileConflicts are the flags that EvolveTiled does not support.
+var tileConflicts = []string{"levels", "optimizer", "polish", "recycle", "weights", "edges", "metric", "sample", "sampleexact", "linear", "stop", "bytes", "coordbits", "colorbits", "polyfile"}
 
 func init() {
 	flag.IntVar(&maxGen, "max", 100000, "the number of generations")
-	flag.IntVar(&polyCount, "poly", 50, "the number of polygons")
+	flag.IntVar(&polyCount, "poly", 50, "the number of polygons (the initial number, with -bytes)")
 	flag.StringVar(&srcImgFile, "source", "images/mona_lisa.jpg", "the source input image file")
 	flag.StringVar(&dstImgFile, "dest", "output.png", "the output image file")
+	flag.StringVar(&svgFile, "svg", "", "if set, also save the output as SVG to this file")
+	flag.IntVar(&maxBytes, "bytes", 0, "if > 0, evolve as many polygons as fit in this many bytes in the .poly format, instead of a fixed number")
+	flag.IntVar(&coordBits, "coordbits", 0, "if > 0, quantize coordinates to this many bits in the .poly format")
+	flag.IntVar(&colorBits, "colorbits", 0, "if between 1 and 7, quantize color channels to this many bits in the .poly format")
+	flag.StringVar(&polyFile, "polyfile", "", "if set, also save the output in the .poly format to this file")
 	flag.StringVar(&cpArg, "cp", "", "checkpoint file")
 	flag.StringVar(&host, "host", "localhost", "which hostname to http listen on")
 	flag.StringVar(&port, "port", "8080", "which port to http listen on")
+	flag.IntVar(&tileSize, "tile", 0, "if > 0, evolve the image in tiles of this size, using -poly polygons per tile")
+	flag.IntVar(&overlap, "overlap", 8, "number of pixels each tile overlaps its neighbors (with -tile)")
+	flag.StringVar(&optimizer, "optimizer", "hill", "search strategy: hill (mutation hill climber), de (differential evolution) or cmaes")
+	flag.IntVar(&polish, "polish", 0, "if > 0, run a polish pass after this many generations without improvement")
+	flag.IntVar(&recycle, "recycle", 0, "if > 0, respawn invisible or degenerate polygons every this many generations")
+	flag.StringVar(&weightFile, "weights", "", "optional grayscale image, the same size as -source, whose brighter areas get more detail")
+	flag.Float64Var(&edgeWeight, "edges", 0, "if > 0, also compare the edges of the output to those of -source, with this weight (requires -metric mae)")
+	flag.BoolVar(&transparent, "transparent", false, "evolve on a transparent canvas, to match (and preserve) the transparency of -source")
+	flag.StringVar(&metric, "metric", "mae", "fitness metric: mae, mse, ssim or deltae")
+	flag.Float64Var(&sample, "sample", 0, "if between 0 and 1, score early generations on this fraction of the pixels, until improvements slow")
+	flag.IntVar(&sampleExact, "sampleexact", polygen.DefaultSampleExactAfter, "with -sample, switch to comparing every pixel after this many generations without improvement")
+	flag.BoolVar(&linear, "linear", false, "blend and compare polygons in linear light (slower, requires -metric mae)")
+	flag.StringVar(&stopArg, "stop", "", "stop early once the output reaches this quality, e.g. psnr:30,ssim:0.9 (measures: similarity, psnr, ssim)")
+	newRenderer = polygen.RendererFlags(flag.CommandLine)
+	flag.StringVar(&levelsArg, "levels", "", "coarse-to-fine schedule of scale:generations pairs to run before full size, e.g. 0.25:2000,0.5:2000")
 
 	flag.Parse()
 
@@ -36,6 +78,16 @@ func init() {
 		os.Exit(1)
 	}
 
+	if tileSize > 0 {
+		flag.Visit(func(f *flag.Flag) {
+			for _, name := range tileConflicts {
+				if f.Name == name {
+					log.Fatalf("-%s is not supported with -tile", name)
+				}
+			}
+		})
+	}
+
 	if port == "" {
 		port = "8080"
 	}
@@ -59,15 +111,63 @@ func main() {
 		previews = append(previews, img)
 	}
 
-	go polygen.Serve(host+":"+port, refImg, previews)
+	status := polygen.NewSafeStatus()
+	go polygen.Serve(host+":"+port, refImg, previews, status)
 
-	cp := polygen.DeriveCheckpointFile(srcImgFile, cpArg, polyCount)
+	r, err := newRenderer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if tileSize > 0 {
+		cp := polygen.DeriveTiledCheckpointFile(srcImgFile, cpArg, polyCount, tileSize, overlap)
+		opts := polygen.TileOptions{Size: tileSize, Overlap: overlap, Polygons: polyCount, Generations: maxGen, Transparent: transparent, Renderer: r}
+		result, err := polygen.EvolveTiled(refImg, dstImgFile, cp, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if svgFile != "" {
+			if err := polygen.SaveSVG(svgFile, result); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		log.Printf("stitched %d polygons, fitness is: %d, saved to %s", len(result.Polygons), result.Fitness, dstImgFile)
+		return
+	}
+
+	levels, err := polygen.ParseLevels(levelsArg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opt, err := polygen.ParseOptimizer(optimizer)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fitness, err := polygen.ParseFitnessFunc(metric)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stopAt, err := polygen.ParseQuality(stopArg)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	evolver, err := polygen.NewEvolver(refImg, dstImgFile, cp)
+	options := polygen.Options{Levels: levels, Optimizer: opt, PolishAfter: polish, RecycleEvery: recycle, EdgeWeight: edgeWeight, Transparent: transparent, Metric: fitness, SampleFraction: sample, SampleExactAfter: sampleExact, Linear: linear, StopAt: stopAt, Status: status, Renderer: r, SVGFile: svgFile, MaxBytes: maxBytes, PolyFile: polyFile}
+	options.Quantization = polygen.Quantization{CoordBits: coordBits, ColorBits: colorBits}
+	if weightFile != "" {
+		options.WeightMap = polygen.MustReadImage(weightFile)
+	}
+
+	cp := polygen.DeriveCheckpointFile(srcImgFile, cpArg, polyCount)
+	evolver, err := polygen.NewEvolver(refImg, dstImgFile, cp, options)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	evolver.Run(maxGen, polyCount, previews)
 }
-
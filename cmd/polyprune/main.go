@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/armhold/polygen"
+)
+
+var (
+	srcImgFile string
+	cpFile     string
+	outCpFile  string
+	dstImgFile string
+	tolerance  float64
+	weightFile string
+	edgeWeight float64
+)
+
+func init() {
+	flag.StringVar(&srcImgFile, "source", "", "the source input image file the checkpoint was evolved from")
+	flag.StringVar(&cpFile, "cp", "", "checkpoint file to prune")
+	flag.StringVar(&outCpFile, "out", "", "output checkpoint file (defaults to the input name with a -pruned suffix)")
+	flag.StringVar(&dstImgFile, "dest", "pruned.png", "the output image file")
+	flag.Float64Var(&tolerance, "tolerance", 0.01, "how much worse the fitness may get, as a fraction of the original")
+	flag.StringVar(&weightFile, "weights", "", "the weight map the checkpoint was evolved with, if any")
+	flag.Float64Var(&edgeWeight, "edges", 0, "the edge weight the checkpoint was evolved with, if any")
+
+	flag.Parse()
+
+	if srcImgFile == "" || cpFile == "" || tolerance < 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if outCpFile == "" {
+		ext := filepath.Ext(cpFile)
+		outCpFile = strings.TrimSuffix(cpFile, ext) + "-pruned" + ext
+	}
+}
+
+func main() {
+	refImg := polygen.MustReadImage(srcImgFile)
+
+	cp, err := polygen.LoadCheckpoint(cpFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// evaluate with the same metric the checkpoint was evolved with
+	metric, err := polygen.ParseFitnessFunc(cp.Metric)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the checkpoint records how its polygons are drawn, but not the weight map or edge weight
+	options := polygen.Options{Metric: metric, Renderer: cp.MostFit.Renderer(), Transparent: cp.MostFit.Transparent, Linear: cp.MostFit.Linear, EdgeWeight: edgeWeight}
+	if weightFile != "" {
+		options.WeightMap = polygen.MustReadImage(weightFile)
+	}
+
+	pruned, err := polygen.Prune(cp.MostFit, refImg, tolerance, options)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cp.MostFit = pruned
+	if err := polygen.SaveCheckpoint(outCpFile, cp); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := polygen.SavePNG(dstImgFile, pruned.Image()); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("saved pruned checkpoint to %s, image to %s", outCpFile, dstImgFile)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"log"
+	"os"
+
+	"github.com/armhold/polygen"
+)
+
+var (
+	cpFile     string
+	dstImgFile string
+	scale      float64
+	width      int
+	renderer   string
+	antialias  bool
+	fillRule   string
+)
+
+func init() {
+	flag.StringVar(&cpFile, "cp", "", "checkpoint file to render")
+	flag.StringVar(&dstImgFile, "dest", "render.png", "the output image file")
+	flag.Float64Var(&scale, "scale", 1, "render at this multiple of the checkpoint's size")
+	flag.IntVar(&width, "width", 0, "if > 0, render at this width (keeping the aspect ratio) instead of using -scale")
+	flag.StringVar(&renderer, "renderer", "", "polygon renderer: draw2d, scanline or vector (default: the checkpoint's)")
+	flag.BoolVar(&antialias, "aa", true, "anti-alias polygon edges (if not given, as the checkpoint was evolved)")
+	flag.StringVar(&fillRule, "fill", "", "fill rule for self-intersecting polygons: evenodd or nonzero (default: the checkpoint's)")
+
+	flag.Parse()
+
+	if cpFile == "" || dstImgFile == "" || scale <= 0 || width < 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+func main() {
+	cp, err := polygen.LoadCheckpoint(cpFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rule, err := polygen.ParseFillRule(fillRule)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// draw the polygons as they were evolved, unless told otherwise
+	name, options := cp.Renderer, cp.RenderOptions
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "renderer":
+			name = renderer
+		case "aa":
+			options.Aliased = !antialias
+		case "fill":
+			options.FillRule = rule
+		}
+	})
+
+	r, err := polygen.ParseRenderer(name, options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cp.MostFit.SetRenderer(r)
+
+	var img *image.RGBA
+	if width > 0 {
+		img, err = cp.MostFit.RenderWidth(width)
+	} else {
+		img, err = cp.MostFit.RenderScaled(scale)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := polygen.SavePNG(dstImgFile, img); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("rendered %dx%d checkpoint at %dx%d, saved to %s", cp.MostFit.W, cp.MostFit.H, img.Bounds().Dx(), img.Bounds().Dy(), dstImgFile)
+}
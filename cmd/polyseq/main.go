@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/armhold/polygen"
+)
+
+var (
+	srcDir      string
+	outDir      string
+	polyCount   int
+	firstGen    int
+	frameGen    int
+	verbose     bool
+	newRenderer func() (polygen.Renderer, error)
+)
+
+func init() {
+	flag.StringVar(&srcDir, "dir", "", "directory of numbered frame images")
+	flag.StringVar(&outDir, "out", "frames", "directory for output frames and per-frame checkpoints")
+	flag.IntVar(&polyCount, "poly", 50, "the number of polygons")
+	flag.IntVar(&firstGen, "first", 20000, "the number of generations for the first frame")
+	flag.IntVar(&frameGen, "max", 2000, "the number of generations for each following frame")
+	newRenderer = polygen.RendererFlags(flag.CommandLine)
+	flag.BoolVar(&verbose, "v", false, "log per-generation statistics")
+
+	flag.Parse()
+
+	if srcDir == "" || outDir == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	rand.Seed(time.Now().UTC().UnixNano())
+}
+
+func main() {
+	entries, err := ioutil.ReadDir(srcDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var frames []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".jpg", ".jpeg", ".png", ".gif":
+			frames = append(frames, filepath.Join(srcDir, entry.Name()))
+		}
+	}
+
+	polygen.SortFrames(frames)
+
+	r, err := newRenderer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	seqOpts := polygen.SequenceOptions{Polygons: polyCount, FirstGenerations: firstGen, Generations: frameGen}
+	if err := polygen.EvolveSequence(frames, outDir, seqOpts, polygen.Options{Quiet: !verbose, Renderer: r}); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("evolved %d frames into %s", len(frames), outDir)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/armhold/polygen"
+)
+
+var (
+	cpFile    string
+	dstFile   string
+	renderer  string
+	antialias bool
+	fillRule  string
+)
+
+func init() {
+	flag.StringVar(&cpFile, "cp", "", "checkpoint file to export")
+	flag.StringVar(&dstFile, "dest", "output.svg", "the output SVG file")
+	flag.StringVar(&renderer, "renderer", "", "polygon renderer, which decides the default fill rule: draw2d, scanline or vector (default: the checkpoint's)")
+	flag.BoolVar(&antialias, "aa", true, "anti-alias polygon edges (if not given, as the checkpoint was evolved)")
+	flag.StringVar(&fillRule, "fill", "", "fill rule for self-intersecting polygons: evenodd or nonzero (default: the checkpoint's)")
+
+	flag.Parse()
+
+	if cpFile == "" || dstFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+func main() {
+	cp, err := polygen.LoadCheckpoint(cpFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rule, err := polygen.ParseFillRule(fillRule)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// draw the polygons as they were evolved, unless told otherwise
+	name, options := cp.Renderer, cp.RenderOptions
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "renderer":
+			name = renderer
+		case "aa":
+			options.Aliased = !antialias
+		case "fill":
+			options.FillRule = rule
+		}
+	})
+
+	r, err := polygen.ParseRenderer(name, options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cp.MostFit.SetRenderer(r)
+
+	if err := polygen.SaveSVG(dstFile, cp.MostFit); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("exported %d polygons to %s", len(cp.MostFit.Polygons), dstFile)
+}
@@ -0,0 +1,58 @@
+package polygen
+
+import (
+	"image"
+	"math"
+)
+
+// sobel returns the edge map of img: the magnitude of the Sobel gradient of its luminance at each pixel,
+// clamped to 255. Pixels along the border use the nearest pixel inside the image for their neighbors.
+func sobel(img *image.RGBA) []uint8 {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+
+	lum := make([]int, w*h)
+	for i := range lum {
+		p := img.Pix[i*4 : i*4+3]
+		lum[i] = (299*int(p[0]) + 587*int(p[1]) + 114*int(p[2])) / 1000
+	}
+
+	at := func(x, y int) int {
+		return lum[clampInt(y, 0, h-1)*w+clampInt(x, 0, w-1)]
+	}
+
+	result := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+
+			mag := math.Sqrt(float64(gx*gx + gy*gy))
+			if mag > 255 {
+				mag = 255
+			}
+			result[y*w+x] = uint8(mag)
+		}
+	}
+
+	return result
+}
+
+// edgeError returns the sum of the absolute differences between two edge maps, optionally scaled by
+// per-pixel weights (see FastCompareWeighted).
+func edgeError(edges1, edges2 []uint8, weights []uint8) uint64 {
+	accumError := uint64(0)
+
+	if weights == nil {
+		for i := range edges1 {
+			accumError += uint64(diffUint8(edges1[i], edges2[i]))
+		}
+
+		return accumError
+	}
+
+	for i := range edges1 {
+		accumError += uint64(diffUint8(edges1[i], edges2[i])) * uint64(weights[i])
+	}
+
+	return accumError / 255
+}
@@ -0,0 +1,56 @@
+package polygen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestSobel(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{0, 0, 255, 255}}, image.ZP, draw.Src)
+
+	for i, e := range sobel(img) {
+		if e != 0 {
+			t.Fatalf("expected no edges in a flat image, got %d at %d", e, i)
+		}
+	}
+
+	// white on the right half gives a vertical edge down the middle
+	draw.Draw(img, image.Rect(5, 0, 10, 10), &image.Uniform{color.White}, image.ZP, draw.Src)
+	edges := sobel(img)
+
+	if edges[5*10+4] != 255 || edges[5*10+5] != 255 {
+		t.Errorf("expected strong edges at the boundary, got: %d, %d", edges[5*10+4], edges[5*10+5])
+	}
+
+	if edges[5*10+1] != 0 || edges[5*10+8] != 0 {
+		t.Errorf("expected no edges away from the boundary, got: %d, %d", edges[5*10+1], edges[5*10+8])
+	}
+}
+
+func TestEdgeError(t *testing.T) {
+	e1 := []uint8{0, 10, 255, 100}
+	e2 := []uint8{10, 0, 255, 50}
+
+	if d := edgeError(e1, e2, nil); d != 70 {
+		t.Errorf("expected 70, got: %d", d)
+	}
+
+	if d := edgeError(e1, e2, []uint8{255, 255, 255, 0}); d != 20 {
+		t.Errorf("expected 20, got: %d", d)
+	}
+}
+
+func TestEdgeWeightMetric(t *testing.T) {
+	ref := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	if _, err := NewEvolver(ref, "", "", Options{EdgeWeight: 1, Metric: &SSIM{}}); err == nil {
+		t.Errorf("expected error for edge weighting with the ssim metric")
+	}
+
+	if _, err := NewEvolver(ref, "", "", Options{EdgeWeight: 1}); err != nil {
+		t.Errorf("unexpected err: %s", err)
+	}
+}
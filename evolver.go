@@ -7,20 +7,126 @@ import (
 	"image"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Evolver uses a genetic algorithm to evolve a set of polygons to approximate an image.
 type Evolver struct {
-	refImgRGBA             *image.RGBA
+	fullRefImgRGBA         *image.RGBA // the reference image at its original size
+	refImgRGBA             *image.RGBA // the reference image at the size of the current Level
+	options                Options
+	metric                 FitnessFunc
 	dstImgFile             string
 	checkPointFile         string
 	candidates             []*Candidate
 	mostFit                *Candidate
 	generation             int
 	generationsSinceChange int
+	weightMap              *image.Gray   // optional per-pixel importance of fullRefImgRGBA
+	weights                []uint8       // weightMap at the size of the current Level, see FastCompareWeighted
+	refEdges               []uint8       // edge map of refImgRGBA, if Options.EdgeWeight is set
+	sample                 []int         // if non-nil, candidates are only compared at these pixels, see Options.SampleFraction
+	refLinear              *image.RGBA64 // refImgRGBA in linear light, if Options.Linear is set
+	statusTime             time.Time     // when Options.Status was last updated, see report
+	polished               *Candidate    // the result of the last polish pass, which need not be polished again
+}
+
+// Options controls optional Evolver behavior. The zero value evolves at full size from the start.
+type Options struct {
+	// Levels is a coarse-to-fine schedule of downscaled evolution stages that are run before
+	// evolving against the full size reference image.
+	Levels []Level
+
+	// Quiet suppresses the periodic statistics and checkpoint logging, e.g. when running several
+	// Evolvers concurrently.
+	Quiet bool
+
+	// Optimizer searches for better candidates. Defaults to the HillClimber, see ParseOptimizer.
+	Optimizer Optimizer
+
+	// PolishAfter, if > 0, runs a deterministic polish pass over the most fit candidate whenever this many
+	// generations have gone by without an improvement. The pass is skipped if the most fit candidate has not
+	// changed since the last one, which could not improve it any further. Only applies to the hill climber.
+	PolishAfter int
+
+	// RecycleEvery, if > 0, checks the most fit candidate for invisible or degenerate polygons every this
+	// many generations, and respawns them where the error is high. Only applies to the hill climber.
+	RecycleEvery int
+
+	// StableOrder disables the mutations that reorder polygons, so that each polygon keeps its index (and
+	// hence its identity) throughout the run. Recycling is skipped as well, since it moves polygons.
+	StableOrder bool
+
+	// WeightMap, if non-nil, is a grayscale image the same size as the reference image that scales the
+	// error of each pixel: white pixels count fully, black pixels not at all.
+	WeightMap image.Image
+
+	// EdgeWeight, if > 0, adds a term to the fitness that compares the edges (Sobel gradient magnitudes) of
+	// the candidate with those of the reference image, which helps preserve outlines. The edge error of each
+	// pixel is scaled by EdgeWeight relative to the error of a single color channel, so it requires the MAE
+	// metric, whose errors are in the same units.
+	EdgeWeight float64
+
+	// Transparent evolves polygons on a transparent (rather than black) canvas, so that the alpha channel of
+	// the reference image is matched as well, and the output image preserves transparency.
+	Transparent bool
+
+	// Metric is used to compare candidates with the reference image. Defaults to MAE.
+	Metric FitnessFunc
+
+	// SampleFraction, if between 0 and 1, speeds up early generations by comparing candidates on only this
+	// fraction of the pixels (a stratified random sample, renewed every SampleResampleEvery generations). Once
+	// SampleExactAfter generations go by without an improvement, the exact fitness is used from then on. Only
+	// applies to the hill climber, with the MAE or MSE metrics and no EdgeWeight.
+	SampleFraction float64
+
+	// SampleExactAfter is the number of generations without an improvement after which sampling (see
+	// SampleFraction) stops. Defaults to DefaultSampleExactAfter.
+	SampleExactAfter int
+
+	// Linear composites polygons in linear light, with 16 bits per channel, and compares them with the reference
+	// image in linear light as well, so that translucent overlaps blend correctly. Only the output is converted
+	// back to sRGB. Requires the MAE metric, and skips the incremental evaluation of the hill climber, so it is
+	// slower.
+	Linear bool
+
+	// StopAt, if non-zero, ends the run early once the most fit candidate reaches every non-zero measure in it
+	// at full size (see Quality.Meets). It is checked every 10 generations, but not while evolving against the
+	// downscaled Levels, whose quality is not comparable to that of the full size image.
+	StopAt Quality
+
+	// Renderer draws the candidates. Defaults to Draw2D.
+	Renderer Renderer
+
+	// MaxBytes, if > 0, limits the most fit candidate to this many bytes in the .poly format, encoded with
+	// Quantization (see EncodePolyQuantized). Rather than evolving a fixed number of polygons, the hill climber
+	// then adds and removes polygons (and points) as the budget allows, rejecting children that exceed it, and
+	// snaps candidates to Quantization as they evolve, moving points in steps of its coordinate grid. Size is not
+	// traded against fitness: any child that fits is judged by fitness alone, so the result tends to fill the
+	// budget rather than stop at a smaller size that looks nearly as good. Only supported by the HillClimber, and
+	// not with Levels, PolishAfter, StableOrder or RecycleEvery.
+	MaxBytes int
+
+	// Quantization is the precision of the .poly encoding used for MaxBytes and PolyFile.
+	Quantization Quantization
+
+	// PolyFile, if non-empty, is where the most fit candidate is saved in the .poly format, whenever the output
+	// image is saved.
+	PolyFile string
+
+	// SVGFile, if non-empty, is where the most fit candidate is saved as SVG (see WriteSVG), whenever the output
+	// image is saved.
+	SVGFile string
+
+	// Status, if non-nil, is updated with the progress of the run at most once per StatusInterval, e.g. for
+	// display by the Server.
+	Status *SafeStatus
 }
 
 // Checkpoint is used for serializing the current best candidate and corresponding generation
@@ -29,16 +135,50 @@ type Checkpoint struct {
 	Generation             int
 	GenerationsSinceChange int
 	MostFit                *Candidate
+	Metric                 string // name of the FitnessFunc that MostFit.Fitness was computed with
+
+	// Renderer and RenderOptions record the Renderer of MostFit (see ParseRenderer): SaveCheckpoint sets them
+	// from MostFit, and LoadCheckpoint applies them to it. An empty Renderer means the default, Draw2D{}.
+	Renderer      string
+	RenderOptions RenderOptions
 }
 
-func NewEvolver(refImg image.Image, dstImageFile string, checkPointFile string) (*Evolver, error) {
+func NewEvolver(refImg image.Image, dstImageFile string, checkPointFile string, options Options) (*Evolver, error) {
 	result := &Evolver{
 		dstImgFile:     dstImageFile,
 		checkPointFile: checkPointFile,
+		options:        options,
+		metric:         options.Metric,
 		candidates:     make([]*Candidate, PopulationCount),
 	}
 
-	result.refImgRGBA = ConvertToRGBA(refImg)
+	if result.metric == nil {
+		result.metric = MAE{}
+	}
+
+	if options.Linear && result.metric.Name() != (MAE{}).Name() {
+		return nil, fmt.Errorf("linear mode does not support the %s metric", result.metric.Name())
+	}
+
+	if options.EdgeWeight > 0 && result.metric.Name() != (MAE{}).Name() {
+		return nil, fmt.Errorf("edge weighting does not support the %s metric", result.metric.Name())
+	}
+
+	if options.MaxBytes > 0 && (!isHillClimber(options.Optimizer) || len(options.Levels) > 0 || options.PolishAfter > 0 || options.StableOrder || options.RecycleEvery > 0) {
+		return nil, fmt.Errorf("a byte budget is only supported by the hill climber, and not with levels, polishing, stable order or recycling")
+	}
+
+	result.fullRefImgRGBA = ConvertToRGBA(refImg)
+
+	if options.WeightMap != nil {
+		if options.WeightMap.Bounds().Size() != refImg.Bounds().Size() {
+			return nil, fmt.Errorf("weight map size %v does not match reference image size %v", options.WeightMap.Bounds().Size(), refImg.Bounds().Size())
+		}
+
+		result.weightMap = ConvertToGray(options.WeightMap)
+	}
+
+	result.setReference(1)
 
 	// if there's an existing checkpoint file, restore from last checkpoint
 	if _, err := os.Stat(checkPointFile); !os.IsNotExist(err) {
@@ -55,42 +195,167 @@ func NewEvolver(refImg image.Image, dstImageFile string, checkPointFile string)
 // At each generation, the candidate images are rendered & evaluated, and the preview images are
 // updated to reflect the current state.
 func (e *Evolver) Run(maxGen, polyCount int, previews []*SafeImage) {
-	w := e.refImgRGBA.Bounds().Dx()
-	h := e.refImgRGBA.Bounds().Dy()
+	w := e.fullRefImgRGBA.Bounds().Dx()
+	h := e.fullRefImgRGBA.Bounds().Dy()
 
 	// no candidate from prev call to RestoreFromCheckpoint()
 	if e.mostFit == nil {
 		e.mostFit = randomCandidate(w, h, polyCount)
 		e.candidates[0] = e.mostFit
 	}
+	e.mostFit.Transparent = e.options.Transparent
+	e.mostFit.Linear = e.options.Linear
+	e.mostFit.renderer = e.options.Renderer
 
 	// TODO: probably move the polyCount arg to NewEvolver(). It makes more sense to check there,
 	// and complain about the checkpoint file by name, which we do not have here.
-	if len(e.mostFit.Polygons) != polyCount {
+	if e.options.MaxBytes > 0 {
+		e.fitBudget()
+	} else if len(e.mostFit.Polygons) != polyCount {
 		log.Fatalf("checkpoint file polygon count mismatch: %d != %d", len(e.mostFit.Polygons), polyCount)
 	}
 
-	e.renderAndEvaluate(e.mostFit)
+	scale, levelEnd := levelAt(e.options.Levels, e.generation)
+	e.enterLevel(scale)
 
 	stats := NewStats()
 
+	if isHillClimber(e.options.Optimizer) {
+		e.runHillClimber(maxGen, levelEnd, stats, previews)
+	} else {
+		e.runOptimizer(maxGen, levelEnd, stats, previews)
+	}
+
+	e.save()
+
+	if !e.options.Quiet || e.options.Status != nil {
+		final := e.fullSize()
+		quality, err := MeasureQuality(e.fullRefImgRGBA, final.img)
+		if err != nil {
+			log.Fatalf("error measuring quality: %s", err)
+		}
+
+		if e.options.Status != nil {
+			e.options.Status.Update(Status{Generation: e.generation, Fitness: e.mostFit.Fitness, Quality: quality})
+		}
+
+		if !e.options.Quiet {
+			log.Printf("after %d generations, fitness is: %d, %s, saved to %s", e.generation, e.mostFit.Fitness, quality, e.dstImgFile)
+
+			if e.options.MaxBytes > 0 {
+				e.reportBudget()
+			}
+		}
+	}
+}
+
+// countGeneration records the end of a generation of evals evaluations, and whether it improved on the most fit
+// candidate.
+func (e *Evolver) countGeneration(stats *Stats, evals int, improved bool) {
+	stats.Increment(evals)
+
+	if improved {
+		e.generationsSinceChange = 0
+	} else {
+		e.generationsSinceChange++
+	}
+}
+
+// reportGeneration reports progress every 10 generations, and saves every 250. Returns true if the run should
+// stop early, see report.
+func (e *Evolver) reportGeneration(stats *Stats, best, worst *Candidate) bool {
+	if e.generation%10 == 0 && e.report(stats, best, worst) {
+		return true
+	}
+
+	if e.generation%250 == 0 {
+		e.save()
+	}
+
+	return false
+}
+
+// runHillClimber evolves the most fit candidate with the HillClimber, by repeatedly mutating copies of it, and
+// keeping the best.
+func (e *Evolver) runHillClimber(maxGen, levelEnd int, stats *Stats, previews []*SafeImage) {
+	var scale float64
+
+	mutations := Mutations
+	if e.options.StableOrder {
+		mutations = nil
+		for _, m := range Mutations {
+			if m != MutationZOrder {
+				mutations = append(mutations, m)
+			}
+		}
+	}
+
+	if e.options.MaxBytes > 0 {
+		mutations = append(mutations[:len(mutations):len(mutations)], MutationAddOrDeletePolygon)
+	}
+
+	e.startSampling()
+
 	// to synchronize workers
 	c := make(chan struct{})
 
+	// the order in which to compare the rows of children, see rowsByError
+	var rows []int
+	var rowsFor *Candidate
+
 	for ; e.generation < maxGen; e.generation++ {
+		if e.generation == levelEnd {
+			scale, levelEnd = levelAt(e.options.Levels, e.generation)
+			e.enterLevel(scale)
+		}
+
+		if e.sample != nil {
+			if e.generationsSinceChange >= e.sampleExactAfter() {
+				e.stopSampling()
+			} else if e.generation%SampleResampleEvery == 0 {
+				e.resample()
+			}
+		}
+
+		parent := e.mostFit
+		if !parent.hasLayers() {
+			parent.renderLayers()
+		}
+		if parent != rowsFor {
+			rows, rowsFor = rowsByError(e.refImgRGBA, parent.img), parent
+		}
 
 		processCandidate := func(cand *Candidate) {
-			for i := 0; i < MutationsPerIteration; i++ {
-				cand.mutateInPlace()
+			dirty, layer := cand.mutate(mutations)
+			for i := 1; i < MutationsPerIteration; i++ {
+				d, l := cand.mutate(mutations)
+				dirty = dirty.Union(d)
+				if l < layer {
+					layer = l
+				}
 			}
 
-			e.renderAndEvaluate(cand)
+			if e.options.MaxBytes > 0 {
+				d, l := cand.quantize(e.options.Quantization)
+				dirty = dirty.Union(d)
+				if l < layer {
+					layer = l
+				}
+
+				if !e.withinBudget(cand) {
+					cand.img, cand.Fitness, cand.rejected = parent.img, math.MaxUint64, true
+					c <- struct{}{}
+					return
+				}
+			}
+
+			e.evaluateChange(parent, cand, dirty, layer, rows)
 			c <- struct{}{}
 		}
 
 		// mostFit is already in slot 0, so start at 1
 		for i := 1; i < PopulationCount; i++ {
-			e.candidates[i] = e.mostFit.copyOf()
+			e.candidates[i] = parent.copyOf()
 			go processCandidate(e.candidates[i])
 		}
 
@@ -99,8 +364,6 @@ func (e *Evolver) Run(maxGen, polyCount int, previews []*SafeImage) {
 			<-c
 		}
 
-		stats.Increment(PopulationCount - 1)
-
 		// after sort, the best will be at [0], worst will be at [len() - 1]
 		sort.Sort(ByFitness(e.candidates))
 
@@ -109,80 +372,370 @@ func (e *Evolver) Run(maxGen, polyCount int, previews []*SafeImage) {
 		}
 
 		currBest := e.candidates[0]
-		worst := e.candidates[len(e.candidates)-1]
 
-		if currBest.Fitness < e.mostFit.Fitness {
-			e.generationsSinceChange = 0
+		// rejected candidates sort last, but were not fully scored
+		worst := currBest
+		for _, cand := range e.candidates {
+			if !cand.rejected {
+				worst = cand
+			}
+		}
+
+		improved := currBest.Fitness < e.mostFit.Fitness
+		if improved {
 			e.mostFit = currBest
-		} else {
-			e.generationsSinceChange++
+		}
+		e.countGeneration(stats, PopulationCount-1, improved)
+
+		if e.options.PolishAfter > 0 && e.generationsSinceChange > 0 && e.generationsSinceChange%e.options.PolishAfter == 0 && e.mostFit != e.polished {
+			e.polish()
 		}
 
-		if e.generation%10 == 0 {
-			stats.Print(currBest, worst, e.generation, e.generationsSinceChange)
+		if e.options.RecycleEvery > 0 && !e.options.StableOrder && e.generation > 0 && e.generation%e.options.RecycleEvery == 0 {
+			stats.Recycled(e.recycle())
 		}
 
-		if e.generation%250 == 0 {
-			cpSave := time.Now()
-			err := e.mostFit.drawAndSave(e.dstImgFile)
-			if err != nil {
-				log.Fatalf("error saving output image: %s", err)
+		if e.reportGeneration(stats, currBest, worst) {
+			e.generation++
+			break
+		}
+	}
+
+	// the final result is always scored exactly
+	if e.sample != nil {
+		e.stopSampling()
+	}
+}
+
+// runOptimizer evolves the most fit candidate with the configured Optimizer (other than the HillClimber), giving
+// it the same number of evaluations per generation as the hill climber. The Optimizer is restarted at each Level.
+func (e *Evolver) runOptimizer(maxGen, levelEnd int, stats *Stats, previews []*SafeImage) {
+	evalsPerGen := PopulationCount - 1
+
+	for e.generation < maxGen {
+		end := maxGen
+		if levelEnd >= 0 && levelEnd < maxGen {
+			end = levelEnd
+		}
+
+		template := e.mostFit
+		x0, lower, upper := template.genome()
+
+		var mu sync.Mutex
+		evals := 0
+		improved := false
+		var worst *Candidate
+		stopped := false
+
+		objective := func(x []float64) float64 {
+			mu.Lock()
+			if stopped {
+				// use up the rest of the budget without evaluating anything
+				mu.Unlock()
+				return math.MaxFloat64
 			}
+			mu.Unlock()
+
+			cand := template.withGenome(x)
+			e.renderAndEvaluate(cand)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if cand.Fitness < e.mostFit.Fitness {
+				e.mostFit = cand
+				e.candidates[0] = cand
+				improved = true
 
-			err = e.saveCheckpoint()
-			if err != nil {
-				log.Fatalf("error saving checkpoint file: %s", err)
+				for i := 0; i < len(previews); i++ {
+					previews[i].Update(cand.img)
+				}
 			}
-			dur := time.Since(cpSave)
-			log.Printf("checkpoint took %s", dur)
+
+			if worst == nil || cand.Fitness > worst.Fitness {
+				worst = cand
+			}
+
+			evals++
+			if evals%evalsPerGen == 0 && e.generation < end {
+				e.countGeneration(stats, evalsPerGen, improved)
+				stopped = e.reportGeneration(stats, e.mostFit, worst)
+
+				e.generation++
+				improved = false
+				worst = nil
+			}
+
+			return float64(cand.Fitness)
+		}
+
+		best := template.withGenome(e.options.Optimizer.Optimize(x0, lower, upper, objective, (end-e.generation)*evalsPerGen))
+		e.renderAndEvaluate(best)
+		if best.Fitness < e.mostFit.Fitness {
+			e.mostFit = best
+			e.candidates[0] = best
+		}
+
+		if stopped {
+			return
+		}
+
+		// the optimizer may stop short of its budget
+		e.generation = end
+
+		if e.generation == levelEnd {
+			var scale float64
+			scale, levelEnd = levelAt(e.options.Levels, e.generation)
+			e.enterLevel(scale)
+		}
+	}
+}
+
+// save writes the output image and checkpoint file, if the Evolver has been configured with them.
+func (e *Evolver) save() {
+	cpSave := time.Now()
+
+	if e.dstImgFile != "" {
+		err := e.fullSize().drawAndSave(e.dstImgFile)
+		if err != nil {
+			log.Fatalf("error saving output image: %s", err)
 		}
 	}
 
-	e.mostFit.drawAndSave(e.dstImgFile)
-	log.Printf("after %d generations, fitness is: %d, saved to %s", maxGen, e.mostFit.Fitness, e.dstImgFile)
+	if e.options.PolyFile != "" {
+		err := SavePoly(e.options.PolyFile, e.fullSize(), e.options.Quantization)
+		if err != nil {
+			log.Fatalf("error saving .poly file: %s", err)
+		}
+	}
+
+	if e.options.SVGFile != "" {
+		err := SaveSVG(e.options.SVGFile, e.fullSize())
+		if err != nil {
+			log.Fatalf("error saving SVG file: %s", err)
+		}
+	}
+
+	if e.checkPointFile != "" {
+		err := e.saveCheckpoint()
+		if err != nil {
+			log.Fatalf("error saving checkpoint file: %s", err)
+		}
+	}
+
+	if !e.options.Quiet {
+		dur := time.Since(cpSave)
+		log.Printf("checkpoint took %s", dur)
+	}
+}
+
+// enterLevel switches the Evolver to the reference image scaled by scale, and rescales the most fit
+// candidate to match.
+func (e *Evolver) enterLevel(scale float64) {
+	w, h := e.setReference(scale)
+
+	if len(e.options.Levels) > 0 {
+		log.Printf("evolving at %dx%d (scale %g) from generation %d", w, h, scale, e.generation)
+	}
+
+	if e.mostFit.W != w || e.mostFit.H != h {
+		e.mostFit = e.mostFit.scaledTo(w, h)
+		e.candidates[0] = e.mostFit
+	}
+
+	e.renderAndEvaluate(e.mostFit)
+}
+
+// setReference sets the reference image (and everything derived from it) to the original scaled by scale,
+// and returns the new dimensions.
+func (e *Evolver) setReference(scale float64) (w, h int) {
+	w, h = e.fullRefImgRGBA.Bounds().Dx(), e.fullRefImgRGBA.Bounds().Dy()
+
+	if scale < 1 {
+		w, h = scaledSize(w, h, scale)
+		e.refImgRGBA = ScaleImage(e.fullRefImgRGBA, w, h)
+
+		if e.weightMap != nil {
+			e.weights = ConvertToGray(ScaleImage(e.weightMap, w, h)).Pix
+		}
+	} else {
+		e.refImgRGBA = e.fullRefImgRGBA
+
+		if e.weightMap != nil {
+			e.weights = e.weightMap.Pix
+		}
+	}
+
+	if e.options.EdgeWeight > 0 {
+		e.refEdges = sobel(e.refImgRGBA)
+	}
+
+	if e.sample != nil {
+		e.sample = stratifiedSample(w, h, e.options.SampleFraction)
+	}
+
+	if e.options.Linear {
+		e.refLinear = rgbaToLinear(e.refImgRGBA)
+	}
+
+	return w, h
+}
+
+// fullSize returns the most fit candidate rendered at the size of the original reference image.
+func (e *Evolver) fullSize() *Candidate {
+	w, h := e.fullRefImgRGBA.Bounds().Dx(), e.fullRefImgRGBA.Bounds().Dy()
+	if e.mostFit.W == w && e.mostFit.H == h {
+		return e.mostFit
+	}
+
+	result := e.mostFit.scaledTo(w, h)
+	result.renderImage()
+
+	return result
 }
 
 func (e *Evolver) restoreFromCheckpoint() error {
-	b, err := ioutil.ReadFile(e.checkPointFile)
+	cp, err := LoadCheckpoint(e.checkPointFile)
 	if err != nil {
-		return fmt.Errorf("error reading checkpoint file: %s: %s", e.checkPointFile, err)
+		return err
 	}
 
-	decoder := gob.NewDecoder(bytes.NewBuffer(b))
-
-	var cp Checkpoint
-	err = decoder.Decode(&cp)
+	// fitness values are only comparable within the same metric
+	cpMetric, err := ParseFitnessFunc(cp.Metric)
 	if err != nil {
-		return fmt.Errorf("error decoding checkpoint file: %s %s", e.checkPointFile, err)
+		return fmt.Errorf("checkpoint file %s: %s", e.checkPointFile, err)
+	}
+	if cpMetric.Name() != e.metric.Name() {
+		return fmt.Errorf("checkpoint file %s was evolved with the %s metric, not %s", e.checkPointFile, cpMetric.Name(), e.metric.Name())
+	}
+
+	if err := cp.checkRenderer(e.checkPointFile, e.options.Renderer); err != nil {
+		return err
 	}
 
 	e.generation = cp.Generation
 	e.generationsSinceChange = cp.GenerationsSinceChange
 	e.candidates[0] = cp.MostFit
 	e.mostFit = cp.MostFit
-	e.renderAndEvaluate(e.mostFit)
 
 	return nil
 }
 
 func (e *Evolver) saveCheckpoint() error {
-	log.Printf("checkpointing to %s", e.checkPointFile)
-
-	buf := new(bytes.Buffer)
-	encoder := gob.NewEncoder(buf)
+	if !e.options.Quiet {
+		log.Printf("checkpointing to %s", e.checkPointFile)
+	}
 
 	cp := &Checkpoint{
 		Generation:             e.generation,
 		GenerationsSinceChange: e.generationsSinceChange,
 		MostFit:                e.mostFit,
+		Metric:                 e.metric.Name(),
+	}
+
+	// while sampling, the fitness of the most fit candidate is only an estimate, so save it with its exact fitness
+	if e.sample != nil {
+		exact := *e.mostFit
+		fitness, err := e.metric.Fitness(e.refImgRGBA, exact.img, e.weights)
+		if err != nil {
+			return err
+		}
+		exact.Fitness = fitness
+		cp.MostFit = &exact
 	}
 
-	err := encoder.Encode(cp)
+	return SaveCheckpoint(e.checkPointFile, cp)
+}
+
+// LoadCheckpoint reads a Checkpoint from the given file, which may be gob encoded (as written by SaveCheckpoint)
+// or a JSON genome (see WriteJSON).
+func LoadCheckpoint(file string) (*Checkpoint, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint file: %s: %s", file, err)
+	}
+
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '{' {
+		cp, err := ReadJSON(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding checkpoint file: %s %s", file, err)
+		}
+
+		return cp, nil
+	}
+
+	decoder := gob.NewDecoder(bytes.NewBuffer(b))
+
+	var cp Checkpoint
+	err = decoder.Decode(&cp)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding checkpoint file: %s %s", file, err)
+	}
+
+	if err := cp.applyRenderer(); err != nil {
+		return nil, fmt.Errorf("error decoding checkpoint file: %s %s", file, err)
+	}
+
+	return &cp, nil
+}
+
+// recordRenderer sets Renderer and RenderOptions from MostFit, see Checkpoint.
+func (cp *Checkpoint) recordRenderer() {
+	cp.Renderer, cp.RenderOptions = "", RenderOptions{}
+	if r := cp.MostFit.renderer; r != nil {
+		cp.Renderer, cp.RenderOptions = r.Name(), rendererOptions(r)
+	}
+}
+
+// applyRenderer sets the Renderer of MostFit from Renderer and RenderOptions, see Checkpoint.
+func (cp *Checkpoint) applyRenderer() error {
+	if cp.Renderer == "" && cp.RenderOptions == (RenderOptions{}) {
+		return nil
+	}
+
+	r, err := ParseRenderer(cp.Renderer, cp.RenderOptions)
+	if err != nil {
+		return err
+	}
+	cp.MostFit.renderer = r
+
+	return nil
+}
+
+// checkRenderer returns an error if the checkpoint (read from file) was not drawn like r would draw it, since
+// its fitness (and further evolution) depend on how it is rendered. A nil r means the default, Draw2D{}.
+func (cp *Checkpoint) checkRenderer(file string, r Renderer) error {
+	if r == nil {
+		r = Draw2D{}
+	}
+
+	have := cp.MostFit.Renderer()
+	if have.Name() != r.Name() || effectiveRenderOptions(have) != effectiveRenderOptions(r) {
+		return fmt.Errorf("checkpoint file %s was rendered with %s, not %s", file, describeRenderer(have), describeRenderer(r))
+	}
+
+	return nil
+}
+
+// SaveCheckpoint writes cp to the given file. Files ending in ".json" are written as JSON genomes (see WriteJSON),
+// anything else is gob encoded.
+func SaveCheckpoint(file string, cp *Checkpoint) error {
+	buf := new(bytes.Buffer)
+
+	saved := *cp
+	saved.recordRenderer()
+
+	var err error
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		err = WriteJSON(buf, &saved)
+	} else {
+		err = gob.NewEncoder(buf).Encode(&saved)
+	}
 	if err != nil {
 		return fmt.Errorf("error encoding checkpoint: %s", err)
 	}
 
-	err = ioutil.WriteFile(e.checkPointFile, buf.Bytes(), 0644)
+	err = ioutil.WriteFile(file, buf.Bytes(), 0644)
 	if err != nil {
 		return fmt.Errorf("error writing checkpoint to file: %s", err)
 	}
@@ -192,12 +745,34 @@ func (e *Evolver) saveCheckpoint() error {
 
 func (e *Evolver) renderAndEvaluate(c *Candidate) {
 	c.renderImage()
+	e.evaluate(c)
+}
+
+// evaluate sets the fitness of c, which must already have been rendered.
+func (e *Evolver) evaluate(c *Candidate) {
+	if e.options.Linear {
+		e.evaluateLinear(c)
+		return
+	}
+
+	if rf, ok := e.regionMetric(); ok {
+		e.evaluateRegion(rf, c)
+		return
+	}
 
-	diff, err := FastCompare(e.refImgRGBA, c.img)
+	if e.sample != nil {
+		c.Fitness = sampleFitness(e.metric.(regionFitnessFunc), e.refImgRGBA, c.img, e.weights, e.sample)
+		return
+	}
 
+	diff, err := e.metric.Fitness(e.refImgRGBA, c.img, e.weights)
 	if err != nil {
 		log.Fatalf("error comparing images: %s", err)
 	}
 
+	if e.options.EdgeWeight > 0 {
+		diff += uint64(e.options.EdgeWeight * float64(edgeError(e.refEdges, sobel(c.img), e.weights)))
+	}
+
 	c.Fitness = diff
 }
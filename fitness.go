@@ -0,0 +1,301 @@
+package polygen
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sync"
+)
+
+const (
+	// ssimWindow is the size of the square windows over which SSIM is computed, and ssimStride the
+	// distance between them.
+	ssimWindow = 8
+	ssimStride = 4
+
+	// SSIM and deltaE are fractional, so they are scaled by these before being converted to a uint64 Fitness.
+	ssimScale   = 1e9
+	deltaEScale = 100
+)
+
+// FitnessFunc measures how far a rendered candidate is from the reference image; lower is better.
+type FitnessFunc interface {
+	// Name identifies the metric, e.g. in checkpoints and on the command line.
+	Name() string
+
+	// Fitness compares img against ref, which must have the same bounds. If weights is non-nil, it holds the
+	// importance of each pixel from 0 to 255, see Options.WeightMap.
+	Fitness(ref, img *image.RGBA, weights []uint8) (uint64, error)
+}
+
+// ParseFitnessFunc returns the FitnessFunc with the given name: "mae", "mse", "ssim" or "deltae". An empty
+// name (as found in checkpoints that predate FitnessFunc) means "mae".
+func ParseFitnessFunc(name string) (FitnessFunc, error) {
+	switch name {
+	case "", "mae":
+		return MAE{}, nil
+	case "mse":
+		return MSE{}, nil
+	case "ssim":
+		return &SSIM{}, nil
+	case "deltae":
+		return &DeltaE{}, nil
+	default:
+		return nil, fmt.Errorf("unknown fitness metric: %q", name)
+	}
+}
+
+// MAE sums the absolute differences of every RGBA byte. This is the original polygen metric, see FastCompare.
+type MAE struct{}
+
+func (MAE) Name() string { return "mae" }
+
+func (MAE) Fitness(ref, img *image.RGBA, weights []uint8) (uint64, error) {
+	if weights != nil {
+		return FastCompareWeighted(ref, img, weights)
+	}
+
+	return FastCompare(ref, img)
+}
+
+// MSE sums the squared differences of every RGBA byte, which penalizes large errors more than MAE does.
+type MSE struct{}
+
+func (MSE) Name() string { return "mse" }
+
+func (MSE) Fitness(ref, img *image.RGBA, weights []uint8) (uint64, error) {
+	if err := checkFitnessArgs(ref, img, weights); err != nil {
+		return 0, err
+	}
+
+	accumError := uint64(0)
+
+	for i := 0; i < len(ref.Pix); i += 4 {
+		d := uint64(0)
+		for j := i; j < i+4; j++ {
+			diff := uint64(diffUint8(ref.Pix[j], img.Pix[j]))
+			d += diff * diff
+		}
+
+		if weights != nil {
+			d *= uint64(weights[i/4])
+		}
+		accumError += d
+	}
+
+	if weights != nil {
+		return accumError / 255, nil
+	}
+
+	return accumError, nil
+}
+
+// SSIM scores images by their structural dissimilarity, i.e. 1 - SSIM, computed over the luminance of
+// overlapping windows. It is less sensitive to small shifts in brightness than MAE or MSE, and more
+// sensitive to loss of structure.
+type SSIM struct {
+	cache lumCache
+}
+
+func (*SSIM) Name() string { return "ssim" }
+
+func (s *SSIM) Fitness(ref, img *image.RGBA, weights []uint8) (uint64, error) {
+	if err := checkFitnessArgs(ref, img, weights); err != nil {
+		return 0, err
+	}
+
+	ssim := meanSSIM(s.cache.luminance(ref), luminance(img), ref.Bounds().Dx(), ref.Bounds().Dy(), weights)
+	return uint64(math.Max(0, 1-ssim) * ssimScale), nil
+}
+
+// DeltaE sums the perceptual color difference (CIE76 deltaE, i.e. the distance in CIELAB space) of every
+// pixel. Differences in alpha are counted as an extra dimension, scaled to the range of L.
+type DeltaE struct {
+	cache labCache
+}
+
+func (*DeltaE) Name() string { return "deltae" }
+
+func (d *DeltaE) Fitness(ref, img *image.RGBA, weights []uint8) (uint64, error) {
+	if err := checkFitnessArgs(ref, img, weights); err != nil {
+		return 0, err
+	}
+
+	refLab := d.cache.lab(ref)
+	accumError := 0.0
+
+	for i := 0; i < len(ref.Pix)/4; i++ {
+		p := img.Pix[i*4 : i*4+4]
+		l, a, b := toLab(p[0], p[1], p[2])
+		r := refLab[i*4 : i*4+4]
+		alpha := float64(p[3]) * 100 / 255
+
+		dl, da, db, dalpha := r[0]-l, r[1]-a, r[2]-b, r[3]-alpha
+		delta := math.Sqrt(dl*dl + da*da + db*db + dalpha*dalpha)
+
+		if weights != nil {
+			delta *= float64(weights[i]) / 255
+		}
+		accumError += delta
+	}
+
+	return uint64(accumError * deltaEScale), nil
+}
+
+func checkFitnessArgs(ref, img *image.RGBA, weights []uint8) error {
+	if ref.Bounds() != img.Bounds() {
+		return fmt.Errorf("image bounds not equal: %+v, %+v", ref.Bounds(), img.Bounds())
+	}
+
+	if weights != nil && len(weights)*4 != len(ref.Pix) {
+		return fmt.Errorf("weights length %d does not match image size %+v", len(weights), ref.Bounds())
+	}
+
+	return nil
+}
+
+// luminance returns the luma of each pixel of img, in the range [0, 255].
+func luminance(img *image.RGBA) []float64 {
+	result := make([]float64, len(img.Pix)/4)
+	for i := range result {
+		p := img.Pix[i*4 : i*4+3]
+		result[i] = 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+	}
+
+	return result
+}
+
+// meanSSIM returns the (optionally weighted) mean SSIM over all windows of two w x h luminance images.
+func meanSSIM(lum1, lum2 []float64, w, h int, weights []uint8) float64 {
+	const c1 = (0.01 * 255) * (0.01 * 255)
+	const c2 = (0.03 * 255) * (0.03 * 255)
+
+	win := ssimWindow
+	if w < win || h < win {
+		win = w
+		if h < win {
+			win = h
+		}
+	}
+
+	sum, totalWeight := 0.0, 0.0
+
+	for y := 0; y+win <= h; y += ssimStride {
+		for x := 0; x+win <= w; x += ssimStride {
+			var sum1, sum2, sq1, sq2, cross, weight float64
+
+			for wy := y; wy < y+win; wy++ {
+				for wx := x; wx < x+win; wx++ {
+					i := wy*w + wx
+					a, b := lum1[i], lum2[i]
+					sum1 += a
+					sum2 += b
+					sq1 += a * a
+					sq2 += b * b
+					cross += a * b
+
+					if weights != nil {
+						weight += float64(weights[i])
+					}
+				}
+			}
+
+			n := float64(win * win)
+			mu1, mu2 := sum1/n, sum2/n
+			var1 := sq1/n - mu1*mu1
+			var2 := sq2/n - mu2*mu2
+			covar := cross/n - mu1*mu2
+
+			ssim := ((2*mu1*mu2 + c1) * (2*covar + c2)) / ((mu1*mu1 + mu2*mu2 + c1) * (var1 + var2 + c2))
+
+			if weights == nil {
+				weight = 1
+			}
+			sum += ssim * weight
+			totalWeight += weight
+		}
+	}
+
+	if totalWeight == 0 {
+		return 1
+	}
+
+	return sum / totalWeight
+}
+
+// sRGBToLinear maps 8-bit sRGB values to linear light in [0, 1].
+var sRGBToLinear [256]float64
+
+func init() {
+	for i := range sRGBToLinear {
+		c := float64(i) / 255
+		if c <= 0.04045 {
+			sRGBToLinear[i] = c / 12.92
+		} else {
+			sRGBToLinear[i] = math.Pow((c+0.055)/1.055, 2.4)
+		}
+	}
+}
+
+// toLab converts an sRGB color to CIELAB, using the D65 white point.
+func toLab(r, g, b uint8) (l, a, bb float64) {
+	lr, lg, lb := sRGBToLinear[r], sRGBToLinear[g], sRGBToLinear[b]
+
+	x := (0.4124564*lr + 0.3575761*lg + 0.1804375*lb) / 0.95047
+	y := 0.2126729*lr + 0.7151522*lg + 0.0721750*lb
+	z := (0.0193339*lr + 0.1191920*lg + 0.9503041*lb) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+
+	fx, fy, fz := f(x), f(y), f(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// lumCache remembers the luminance of the most recently used reference image, which rarely changes.
+type lumCache struct {
+	mux    sync.Mutex
+	ref    *image.RGBA
+	values []float64
+}
+
+func (c *lumCache) luminance(ref *image.RGBA) []float64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.ref != ref {
+		c.ref = ref
+		c.values = luminance(ref)
+	}
+
+	return c.values
+}
+
+// labCache remembers the CIELAB colors (plus scaled alpha) of the most recently used reference image.
+type labCache struct {
+	mux    sync.Mutex
+	ref    *image.RGBA
+	values []float64
+}
+
+func (c *labCache) lab(ref *image.RGBA) []float64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.ref != ref {
+		c.ref = ref
+		c.values = make([]float64, len(ref.Pix))
+
+		for i := 0; i < len(ref.Pix); i += 4 {
+			l, a, b := toLab(ref.Pix[i], ref.Pix[i+1], ref.Pix[i+2])
+			c.values[i], c.values[i+1], c.values[i+2] = l, a, b
+			c.values[i+3] = float64(ref.Pix[i+3]) * 100 / 255
+		}
+	}
+
+	return c.values
+}
@@ -0,0 +1,108 @@
+package polygen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func blueImages() (*image.RGBA, *image.RGBA) {
+	rect := image.Rect(0, 0, 100, 100)
+	img1 := image.NewRGBA(rect)
+	img2 := image.NewRGBA(rect)
+
+	draw.Draw(img1, img1.Bounds(), &image.Uniform{color.RGBA{0, 0, 255, 255}}, image.ZP, draw.Src)
+	draw.Draw(img2, img2.Bounds(), &image.Uniform{color.RGBA{0, 0, 250, 255}}, image.ZP, draw.Src)
+
+	return img1, img2
+}
+
+func TestFitnessFuncs(t *testing.T) {
+	img1, _ := blueImages()
+
+	ref := ConvertToRGBA(MustReadImage("images/mona_lisa.jpg"))
+	c := randomCandidate(ref.Bounds().Dx(), ref.Bounds().Dy(), 10)
+	c.renderImage()
+
+	for _, name := range []string{"mae", "mse", "ssim", "deltae"} {
+		f, err := ParseFitnessFunc(name)
+		if err != nil {
+			t.Fatalf("unexpected err: %s", err)
+		}
+
+		if f.Name() != name {
+			t.Errorf("expected name %s, got: %s", name, f.Name())
+		}
+
+		if d, _ := f.Fitness(img1, img1, nil); d != 0 {
+			t.Errorf("%s: expected 0 for identical images, got: %d", name, d)
+		}
+
+		if d, _ := f.Fitness(ref, c.img, nil); d == 0 {
+			t.Errorf("%s: expected non-zero for different images", name)
+		}
+
+		if _, err := f.Fitness(img1, image.NewRGBA(image.Rect(0, 0, 10, 10)), nil); err == nil {
+			t.Errorf("%s: expected error for mismatched bounds", name)
+		}
+
+		// zero weights ignore everything
+		if d, _ := f.Fitness(ref, c.img, make([]uint8, len(ref.Pix)/4)); d != 0 {
+			t.Errorf("%s: expected 0 with all-zero weights, got: %d", name, d)
+		}
+	}
+
+	if _, err := ParseFitnessFunc("bogus"); err == nil {
+		t.Errorf("expected error for unknown metric")
+	}
+}
+
+func TestMSE(t *testing.T) {
+	img1, img2 := blueImages()
+
+	diff, _ := MSE{}.Fitness(img1, img2, nil)
+	expected := uint64(100 * 100 * 5 * 5)
+	if diff != expected {
+		t.Fatalf("expected diff to be %d, got: %d", expected, diff)
+	}
+}
+
+func TestToLab(t *testing.T) {
+	l, a, b := toLab(255, 255, 255)
+	if l < 99.9 || l > 100.1 || a*a > 0.01 || b*b > 0.01 {
+		t.Errorf("expected white to be L=100, a=b=0, got: %g, %g, %g", l, a, b)
+	}
+
+	l, _, _ = toLab(0, 0, 0)
+	if l != 0 {
+		t.Errorf("expected black to be L=0, got: %g", l)
+	}
+}
+
+func TestCheckpointMetricMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "polygen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cpFile := filepath.Join(dir, "cp.tmp")
+	cp := &Checkpoint{MostFit: randomCandidate(10, 10, 5), Metric: "ssim"}
+	if err := SaveCheckpoint(cpFile, cp); err != nil {
+		t.Fatal(err)
+	}
+
+	ref := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	if _, err := NewEvolver(ref, "", cpFile, Options{}); err == nil {
+		t.Fatalf("expected error resuming an ssim checkpoint with mae")
+	}
+
+	if _, err := NewEvolver(ref, "", cpFile, Options{Metric: &SSIM{}}); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+}
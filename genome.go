@@ -0,0 +1,65 @@
+package polygen
+
+import (
+	"image/color"
+	"math"
+)
+
+// genome flattens the continuous parameters of the Candidate into a vector: the coordinates of every point,
+// followed by the non-premultiplied RGBA values of each polygon. The structure of the Candidate (the number of
+// polygons, their points, and their z-order) is not part of the genome. Also returns the lower and upper bounds
+// of each gene.
+func (c *Candidate) genome() (x, lower, upper []float64) {
+	for _, poly := range c.Polygons {
+		for _, p := range poly.Points {
+			x = append(x, float64(p.X), float64(p.Y))
+			lower = append(lower, 0, 0)
+			upper = append(upper, float64(c.W-1), float64(c.H-1))
+		}
+
+		nrgba := color.NRGBAModel.Convert(poly.Color).(color.NRGBA)
+		x = append(x, float64(nrgba.R), float64(nrgba.G), float64(nrgba.B), float64(nrgba.A))
+		lower = append(lower, 0, 0, 0, 0)
+		upper = append(upper, 255, 255, 255, 255)
+	}
+
+	return x, lower, upper
+}
+
+// withGenome returns a copy of the Candidate whose continuous parameters have been replaced by those in x,
+// which must have been laid out by genome(). Values are rounded and clamped to their valid ranges.
+func (c *Candidate) withGenome(x []float64) *Candidate {
+	result := c.copyOf()
+
+	i := 0
+	for _, poly := range result.Polygons {
+		for j := range poly.Points {
+			poly.Points[j] = Point{clampGene(x[i], c.W-1), clampGene(x[i+1], c.H-1)}
+			i += 2
+		}
+
+		nrgba := color.NRGBA{
+			R: uint8(clampGene(x[i], 255)),
+			G: uint8(clampGene(x[i+1], 255)),
+			B: uint8(clampGene(x[i+2], 255)),
+			A: uint8(clampGene(x[i+3], 255)),
+		}
+		poly.Color = color.RGBAModel.Convert(nrgba)
+		i += 4
+	}
+
+	return result
+}
+
+// clampGene rounds v to the nearest int in [0, max].
+func clampGene(v float64, max int) int {
+	i := int(math.Round(v))
+	if i < 0 {
+		return 0
+	}
+	if i > max {
+		return max
+	}
+
+	return i
+}
@@ -1,3 +1,16 @@
 module github.com/armhold/polygen
 
-require github.com/llgcode/draw2d v0.0.0-20180825133448-f52c8a71aff0
+go 1.27.1
+
+require (
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
+	github.com/llgcode/draw2d v0.0.0-20180825133448-f52c8a71aff0
+	golang.org/x/image v0.0.0-20180708004352-c73c2afc3b81
+)
+
+require (
+	github.com/go-gl/gl v0.0.0-20180407155706-68e253793080 // indirect
+	github.com/go-gl/glfw v0.0.0-20180426074136-46a8d530c326 // indirect
+	github.com/jung-kurt/gofpdf v1.0.0 // indirect
+	github.com/llgcode/ps v0.0.0-20150911083025-f1443b32eedb // indirect
+)
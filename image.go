@@ -6,25 +6,46 @@ import (
 	"image/draw"
 	_ "image/gif" // register image formats
 	_ "image/jpeg"
-	_ "image/png"
+	"image/png"
 	"log"
 	"math"
 	"os"
 )
 
 func MustReadImage(file string) image.Image {
-	infile, err := os.Open(file)
+	img, err := ReadImage(file)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	return img
+}
+
+// ReadImage reads and decodes the given image file, in any of the registered formats.
+func ReadImage(file string) (image.Image, error) {
+	infile, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
 	defer infile.Close()
 
 	img, _, err := image.Decode(infile)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("error decoding %s: %s", file, err)
 	}
 
-	return img
+	return img, nil
+}
+
+// SavePNG writes img to the given file in PNG format.
+func SavePNG(file string, img image.Image) error {
+	outfile, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer outfile.Close()
+
+	return png.Encode(outfile, img)
 }
 
 // Compare compares images by computing the square root of the total sum of individual squared pixel differences.
@@ -70,6 +91,62 @@ func FastCompare(img1, img2 *image.RGBA) (uint64, error) {
 	return accumError, nil
 }
 
+// FastCompareBounded is like FastCompare, but stops as soon as the error exceeds bound, in which case the error so
+// far is returned with aborted set to true. The images are compared row by row in the given order (which must list
+// each row of img1 once), or top to bottom if rows is nil, so that rows likely to have large errors can be
+// compared first.
+func FastCompareBounded(img1, img2 *image.RGBA, bound uint64, rows []int) (accumError uint64, aborted bool, err error) {
+	if img1.Bounds() != img2.Bounds() {
+		return 0, false, fmt.Errorf("image bounds not equal: %+v, %+v", img1.Bounds(), img2.Bounds())
+	}
+
+	rowLen := img1.Bounds().Dx() * 4
+
+	for i := 0; i < img1.Bounds().Dy(); i++ {
+		y := i
+		if rows != nil {
+			y = rows[i]
+		}
+
+		start := y * img1.Stride
+		pix1, pix2 := img1.Pix[start:start+rowLen], img2.Pix[start:start+rowLen]
+		for j := range pix1 {
+			accumError += uint64(diffUint8(pix1[j], pix2[j]))
+		}
+
+		if accumError > bound {
+			return accumError, true, nil
+		}
+	}
+
+	return accumError, false, nil
+}
+
+// FastCompareWeighted is like FastCompare, but scales the error of each pixel by the corresponding entry in
+// weights, which must contain one value per pixel. A weight of 255 counts the pixel fully, 0 ignores it.
+func FastCompareWeighted(img1, img2 *image.RGBA, weights []uint8) (uint64, error) {
+	if img1.Bounds() != img2.Bounds() {
+		return 0, fmt.Errorf("image bounds not equal: %+v, %+v", img1.Bounds(), img2.Bounds())
+	}
+
+	if len(weights)*4 != len(img1.Pix) {
+		return 0, fmt.Errorf("weights length %d does not match image size %+v", len(weights), img1.Bounds())
+	}
+
+	accumError := uint64(0)
+
+	for i, w := range weights {
+		j := i * 4
+		d := uint64(diffUint8(img1.Pix[j], img2.Pix[j])) +
+			uint64(diffUint8(img1.Pix[j+1], img2.Pix[j+1])) +
+			uint64(diffUint8(img1.Pix[j+2], img2.Pix[j+2])) +
+			uint64(diffUint8(img1.Pix[j+3], img2.Pix[j+3]))
+		accumError += d * uint64(w)
+	}
+
+	return accumError / 255, nil
+}
+
 // from http://blog.golang.org/go-imagedraw-package ("Converting an Image to RGBA"),
 // modified slightly to be a no-op if the src image is already RGBA
 //
@@ -86,6 +163,15 @@ func ConvertToRGBA(img image.Image) (result *image.RGBA) {
 	return
 }
 
+// ConvertToGray returns a grayscale copy of img, with its bounds translated to the origin.
+func ConvertToGray(img image.Image) *image.Gray {
+	b := img.Bounds()
+	result := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(result, result.Bounds(), img, b.Min, draw.Src)
+
+	return result
+}
+
 // taken directly from image/color/color.go:
 //
 // sqDiff returns the squared-difference of x and y, shifted by 2 so that
@@ -4,6 +4,7 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -76,6 +77,46 @@ func TestFastCompare(t *testing.T) {
 	}
 }
 
+func TestFastCompareWeighted(t *testing.T) {
+	rect := image.Rect(0, 0, 100, 100)
+	img1 := image.NewRGBA(rect)
+	img2 := image.NewRGBA(rect)
+
+	blue1 := color.RGBA{0, 0, 255, 255}
+	blue2 := color.RGBA{0, 0, 250, 255}
+
+	draw.Draw(img1, img1.Bounds(), &image.Uniform{blue1}, image.ZP, draw.Src)
+	draw.Draw(img2, img2.Bounds(), &image.Uniform{blue2}, image.ZP, draw.Src)
+
+	weights := make([]uint8, 100*100)
+	for i := range weights {
+		weights[i] = 255
+	}
+
+	// full weight should be the same as FastCompare
+	diff, _ := FastCompareWeighted(img1, img2, weights)
+	expected := uint64(50000)
+	if diff != expected {
+		t.Fatalf("expected diff to be %d, got: %d", expected, diff)
+	}
+
+	// ignore the top half of the image
+	for i := 0; i < len(weights)/2; i++ {
+		weights[i] = 0
+	}
+
+	diff, _ = FastCompareWeighted(img1, img2, weights)
+	expected = uint64(25000)
+	if diff != expected {
+		t.Fatalf("expected diff to be %d, got: %d", expected, diff)
+	}
+
+	_, err := FastCompareWeighted(img1, img2, weights[1:])
+	if err == nil {
+		t.Fatalf("expected error for mismatched weights")
+	}
+}
+
 func BenchmarkCompare(b *testing.B) {
 	rect := image.Rect(0, 0, 1000, 1000)
 	img1 := image.NewRGBA(rect)
@@ -150,3 +191,53 @@ func TestCompareMonaLisa(t *testing.T) {
 		t.Fatalf("expected diff to be %d, got: %d", expected, diff)
 	}
 }
+
+func TestWeightMap(t *testing.T) {
+	ref := ConvertToRGBA(MustReadImage("images/mona_lisa.jpg"))
+
+	_, err := NewEvolver(ref, "", "", Options{WeightMap: image.NewGray(image.Rect(0, 0, 10, 10))})
+	if err == nil {
+		t.Fatalf("expected error for mismatched weight map size")
+	}
+
+	// an all-black weight map ignores every pixel
+	e, err := NewEvolver(ref, "", "", Options{WeightMap: image.NewGray(ref.Bounds())})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	c := randomCandidate(ref.Bounds().Dx(), ref.Bounds().Dy(), 10)
+	e.renderAndEvaluate(c)
+	if c.Fitness != 0 {
+		t.Fatalf("expected fitness of 0, got: %d", c.Fitness)
+	}
+}
+
+func TestFastCompareBounded(t *testing.T) {
+	img1 := image.NewRGBA(image.Rect(0, 0, 10, 4))
+	img2 := image.NewRGBA(img1.Bounds())
+
+	// all of the error is in the last row
+	for x := 0; x < 10; x++ {
+		img2.Set(x, 3, color.RGBA{100, 0, 0, 0})
+	}
+
+	diff, aborted, err := FastCompareBounded(img1, img2, math.MaxUint64, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != 1000 || aborted {
+		t.Errorf("expected 1000 without abort, got: %d, aborted: %v", diff, aborted)
+	}
+
+	// comparing the last row first should abort before the other rows
+	diff, aborted, _ = FastCompareBounded(img1, img2, 500, []int{3, 0, 1, 2})
+	if diff != 1000 || !aborted {
+		t.Errorf("expected abort at 1000, got: %d, aborted: %v", diff, aborted)
+	}
+
+	diff, aborted, _ = FastCompareBounded(img1, img2, 1000, []int{3, 0, 1, 2})
+	if diff != 1000 || aborted {
+		t.Errorf("expected 1000 without abort at the bound, got: %d, aborted: %v", diff, aborted)
+	}
+}
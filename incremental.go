@@ -0,0 +1,290 @@
+package polygen
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+const (
+	// regionMargin is the number of extra pixels drawn around a region by renderChange. The rasterizer treats
+	// the edges of its canvas differently from the interior, so they must be kept away from the pixels that
+	// are kept.
+	regionMargin = 2
+
+	// maxCachedLayers limits the number of partial renders cached per Candidate by renderLayers.
+	maxCachedLayers = 16
+)
+
+// regionFitnessFunc is implemented by metrics that are a plain sum of per-pixel errors, so that the fitness of
+// a Candidate that differs from its parent only within a region can be updated by re-comparing just that region.
+type regionFitnessFunc interface {
+	// regionFitness returns the contribution of the pixels within r to the raw error of img: Fitness(ref, img,
+	// weights), but without dividing weighted errors by 255 (see rawScale), so that it is an exact sum. The rows
+	// of r are visited in the order given by rows (all rows of ref, some of which may lie outside r), or top to
+	// bottom if rows is nil. As soon as the sum exceeds bound, it returns early with aborted set to true.
+	regionFitness(ref, img *image.RGBA, weights []uint8, r image.Rectangle, rows []int, bound uint64) (fitness uint64, aborted bool)
+
+	// pixelError returns the unweighted error of a single pixel, given the 4 RGBA bytes of each image.
+	pixelError(p1, p2 []uint8) uint64
+}
+
+func (m MAE) regionFitness(ref, img *image.RGBA, weights []uint8, r image.Rectangle, rows []int, bound uint64) (uint64, bool) {
+	if weights == nil && r == ref.Bounds() {
+		fitness, aborted, _ := FastCompareBounded(ref, img, bound, rows)
+		return fitness, aborted
+	}
+
+	return regionError(ref, img, weights, r, rows, bound, m.pixelError)
+}
+
+func (MAE) pixelError(p1, p2 []uint8) uint64 {
+	return uint64(diffUint8(p1[0], p2[0])) + uint64(diffUint8(p1[1], p2[1])) +
+		uint64(diffUint8(p1[2], p2[2])) + uint64(diffUint8(p1[3], p2[3]))
+}
+
+func (m MSE) regionFitness(ref, img *image.RGBA, weights []uint8, r image.Rectangle, rows []int, bound uint64) (uint64, bool) {
+	return regionError(ref, img, weights, r, rows, bound, m.pixelError)
+}
+
+func (MSE) pixelError(p1, p2 []uint8) uint64 {
+	d := uint64(0)
+	for j := range p1 {
+		diff := uint64(diffUint8(p1[j], p2[j]))
+		d += diff * diff
+	}
+
+	return d
+}
+
+// rawScale returns the factor between the raw error of a candidate (see regionFitness) and its Fitness.
+func rawScale(weights []uint8) uint64 {
+	if weights != nil {
+		return 255
+	}
+
+	return 1
+}
+
+// regionError sums pixelError over the pixels of r, multiplied by their weights if non-nil. See regionFitness for
+// rows and bound.
+func regionError(ref, img *image.RGBA, weights []uint8, r image.Rectangle, rows []int, bound uint64, pixelError func(p1, p2 []uint8) uint64) (uint64, bool) {
+	accumError := uint64(0)
+
+	sumRow := func(y int) {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := ref.PixOffset(x, y)
+			d := pixelError(ref.Pix[i:i+4], img.Pix[i:i+4])
+
+			if weights != nil {
+				d *= uint64(weights[i/4])
+			}
+			accumError += d
+		}
+	}
+
+	if rows == nil {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			sumRow(y)
+			if accumError > bound {
+				return accumError, true
+			}
+		}
+
+		return accumError, false
+	}
+
+	for _, y := range rows {
+		if y < r.Min.Y || y >= r.Max.Y {
+			continue
+		}
+
+		sumRow(y)
+		if accumError > bound {
+			return accumError, true
+		}
+	}
+
+	return accumError, false
+}
+
+// rowsByError returns the rows of img, ordered from the highest error (compared to ref) to the lowest. Children of
+// img are likely to have high errors in the same places, so comparing them in this order lets a bounded
+// comparison abort early.
+func rowsByError(ref, img *image.RGBA) []int {
+	h := ref.Bounds().Dy()
+	rows := make([]int, h)
+	errors := make([]uint64, h)
+
+	for y := 0; y < h; y++ {
+		rows[y] = y
+
+		start := ref.PixOffset(0, y)
+		end := start + ref.Bounds().Dx()*4
+		for i := start; i < end; i++ {
+			errors[y] += uint64(diffUint8(ref.Pix[i], img.Pix[i]))
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool { return errors[rows[i]] > errors[rows[j]] })
+
+	return rows
+}
+
+// layerStride returns the number of polygons between the cached layers of the Candidate.
+func (cd *Candidate) layerStride() int {
+	if len(cd.Polygons) <= maxCachedLayers {
+		return 1
+	}
+
+	return (len(cd.Polygons) + maxCachedLayers - 1) / maxCachedLayers
+}
+
+// renderLayers renders the Candidate, caching the composite after every layerStride() polygons so that mutated
+// copies can be redrawn from the first polygon that changed (see renderChange). Layers that are already cached
+// are reused.
+func (cd *Candidate) renderLayers() {
+	if cd.Linear {
+		cd.renderImage()
+		return
+	}
+
+	stride := cd.layerStride()
+
+	img := image.NewRGBA(cd.bounds())
+	from := 0
+	if n := len(cd.layers); n > 0 {
+		copy(img.Pix, cd.layers[n-1].Pix)
+		from = n * stride
+	}
+
+	for from < len(cd.Polygons) {
+		to := from + stride
+		if to > len(cd.Polygons) {
+			to = len(cd.Polygons)
+		}
+
+		cd.paint(img, image.Point{}, img.Bounds(), from, to)
+
+		if to < len(cd.Polygons) {
+			layer := image.NewRGBA(img.Rect)
+			copy(layer.Pix, img.Pix)
+			cd.layers = append(cd.layers, layer)
+		}
+		from = to
+	}
+
+	cd.img = img
+}
+
+// hasLayers reports whether all of the Candidate's layers are cached. Linear candidates are not layered.
+func (cd *Candidate) hasLayers() bool {
+	return cd.Linear || len(cd.layers) == (len(cd.Polygons)-1)/cd.layerStride()
+}
+
+// renderChange renders the Candidate, which is a mutated copy of parent that differs from it only within dirty,
+// and only in the polygons from index layer upwards. Starts from the last of parent's cached layers below
+// layer (which the Candidate inherits), and if dirty is not the whole image, only redraws dirty.
+func (cd *Candidate) renderChange(parent *Candidate, dirty image.Rectangle, layer int) {
+	if cd.Linear {
+		cd.renderImage()
+		return
+	}
+
+	valid := layer / cd.layerStride()
+	if valid > len(parent.layers) {
+		valid = len(parent.layers)
+	}
+	cd.layers = parent.layers[:valid:valid]
+
+	var base *image.RGBA
+	from := 0
+	if valid > 0 {
+		base = cd.layers[valid-1]
+		from = valid * cd.layerStride()
+	}
+
+	if dirty == cd.bounds() {
+		cd.img = image.NewRGBA(cd.bounds())
+		if base != nil {
+			copy(cd.img.Pix, base.Pix)
+		}
+		cd.paint(cd.img, image.Point{}, dirty, from, len(cd.Polygons))
+		return
+	}
+
+	cd.img = image.NewRGBA(parent.img.Rect)
+	copy(cd.img.Pix, parent.img.Pix)
+
+	m := dirty.Inset(-regionMargin)
+	tmp := image.NewRGBA(image.Rect(0, 0, m.Dx(), m.Dy()))
+	if base != nil {
+		draw.Draw(tmp, tmp.Bounds(), base, m.Min, draw.Src)
+	}
+	cd.paint(tmp, m.Min, dirty, from, len(cd.Polygons))
+
+	for y := dirty.Min.Y; y < dirty.Max.Y; y++ {
+		src := tmp.PixOffset(dirty.Min.X-m.Min.X, y-m.Min.Y)
+		dst := cd.img.PixOffset(dirty.Min.X, y)
+		copy(cd.img.Pix[dst:dst+dirty.Dx()*4], tmp.Pix[src:src+dirty.Dx()*4])
+	}
+}
+
+// regionMetric returns the metric as a regionFitnessFunc, if candidates can be evaluated with it: the metric is a
+// plain sum of per-pixel errors, and no other terms (edges, sampling, linear light) are involved.
+func (e *Evolver) regionMetric() (regionFitnessFunc, bool) {
+	rf, ok := e.metric.(regionFitnessFunc)
+	if !ok || e.options.EdgeWeight > 0 || e.sample != nil || e.options.Linear {
+		return nil, false
+	}
+
+	return rf, true
+}
+
+// evaluateRegion sets the fitness of c, which must already have been rendered, with the region metric rf. Unlike
+// evaluate, it also records the raw error that evaluateChange updates for c's children.
+func (e *Evolver) evaluateRegion(rf regionFitnessFunc, c *Candidate) {
+	raw, _ := rf.regionFitness(e.refImgRGBA, c.img, e.weights, c.bounds(), nil, math.MaxUint64)
+	c.rawFitness, c.Fitness = raw, raw/rawScale(e.weights)
+}
+
+// evaluateChange renders and evaluates c, a mutated copy of parent (see renderChange). If the metric allows, only
+// dirty is compared, and the raw error is updated from that of parent. Otherwise (or while sampling) c is
+// evaluated as usual.
+//
+// If the metric allows, the comparison is also aborted as soon as c is known to be worse than parent, in which
+// case c is marked as rejected and its Fitness set to math.MaxUint64. Rows are compared in the order of rows
+// (see rowsByError), which may be nil.
+func (e *Evolver) evaluateChange(parent, c *Candidate, dirty image.Rectangle, layer int, rows []int) {
+	c.renderChange(parent, dirty, layer)
+
+	rf, ok := e.regionMetric()
+	if !ok {
+		e.evaluate(c)
+		return
+	}
+
+	// c is worse than parent if its normalized fitness is higher, i.e. if its raw error exceeds limit
+	scale := rawScale(e.weights)
+	limit := (parent.Fitness+1)*scale - 1
+
+	var raw uint64
+	var aborted bool
+
+	if dirty == c.bounds() {
+		raw, aborted = rf.regionFitness(e.refImgRGBA, c.img, e.weights, dirty, rows, limit)
+	} else {
+		before, _ := rf.regionFitness(e.refImgRGBA, parent.img, e.weights, dirty, nil, math.MaxUint64)
+		after, abortedAfter := rf.regionFitness(e.refImgRGBA, c.img, e.weights, dirty, rows, limit-parent.rawFitness+before)
+		raw, aborted = parent.rawFitness-before+after, abortedAfter
+	}
+
+	if aborted {
+		c.Fitness = math.MaxUint64
+		c.rejected = true
+		return
+	}
+
+	c.rawFitness, c.Fitness = raw, raw/scale
+}
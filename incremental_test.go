@@ -0,0 +1,135 @@
+package polygen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestRenderChange(t *testing.T) {
+	for _, r := range allRenderers() {
+		for _, transparent := range []bool{false, true} {
+			parent := randomCandidate(60, 40, 20)
+			parent.Transparent = transparent
+			parent.renderer = r
+			parent.renderLayers()
+
+			for i := 0; i < 200; i++ {
+				child := parent.copyOf()
+				dirty, layer := child.mutate(Mutations)
+				child.renderChange(parent, dirty, layer)
+
+				full := child.copyOf()
+				full.renderImage()
+
+				if !bytes.Equal(child.img.Pix, full.img.Pix) {
+					t.Fatalf("%s, transparent=%v, mutation %d: render of %v from layer %d differs from full render", describeRenderer(r), transparent, i, dirty, layer)
+				}
+
+				// only some children get their layers completed, as in the Evolver
+				if rand.Intn(2) == 0 {
+					child.renderLayers()
+					if !bytes.Equal(child.img.Pix, full.img.Pix) {
+						t.Fatalf("%s, transparent=%v, mutation %d: layered render differs from full render", describeRenderer(r), transparent, i)
+					}
+				}
+				parent = child
+			}
+		}
+	}
+}
+
+func TestEvaluateChange(t *testing.T) {
+	ref := image.NewRGBA(image.Rect(0, 0, 50, 30))
+	for i := range ref.Pix {
+		ref.Pix[i] = uint8(rand.Intn(256))
+	}
+
+	weightMap := image.NewGray(ref.Bounds())
+	for i := range weightMap.Pix {
+		weightMap.Pix[i] = uint8(rand.Intn(256))
+	}
+
+	cases := []Options{
+		{Metric: MAE{}},
+		{Metric: MSE{}},
+		{Metric: MAE{}, WeightMap: weightMap},
+		{Metric: MSE{}, WeightMap: weightMap, Transparent: true},
+	}
+
+	for _, options := range cases {
+		for _, r := range allRenderers() {
+			options.Renderer = r
+			e, err := NewEvolver(ref, "", "", options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parent := randomCandidate(50, 30, 15)
+			parent.Transparent = options.Transparent
+			parent.renderer = r
+			parent.renderLayers()
+			e.evaluate(parent)
+			rows := rowsByError(ref, parent.img)
+			rejected := 0
+
+			for i := 0; i < 150; i++ {
+				child := parent.copyOf()
+				dirty, layer := child.mutate(Mutations)
+				e.evaluateChange(parent, child, dirty, layer, rows)
+
+				full := child.copyOf()
+				e.renderAndEvaluate(full)
+
+				if expected, _ := options.Metric.Fitness(ref, full.img, e.weights); full.Fitness != expected {
+					t.Fatalf("%s (weighted: %v), %s, mutation %d: fitness %d != metric fitness %d", options.Metric.Name(), options.WeightMap != nil, describeRenderer(r), i, full.Fitness, expected)
+				}
+
+				if child.rejected {
+					rejected++
+					if full.Fitness <= parent.Fitness {
+						t.Fatalf("%s (weighted: %v), %s, mutation %d: rejected, but full fitness %d <= parent fitness %d", options.Metric.Name(), options.WeightMap != nil, describeRenderer(r), i, full.Fitness, parent.Fitness)
+					}
+					continue
+				}
+
+				if child.Fitness != full.Fitness {
+					t.Fatalf("%s (weighted: %v), %s, mutation %d: incremental fitness %d != full fitness %d", options.Metric.Name(), options.WeightMap != nil, describeRenderer(r), i, child.Fitness, full.Fitness)
+				}
+
+				// walk through a variety of candidates, not just mutations of the first
+				if rand.Intn(2) == 0 {
+					child.renderLayers()
+					parent = child
+					rows = rowsByError(ref, parent.img)
+				}
+			}
+
+			if rejected == 0 {
+				t.Errorf("%s (weighted: %v), %s: expected some children to be rejected early", options.Metric.Name(), options.WeightMap != nil, describeRenderer(r))
+			}
+		}
+	}
+}
+
+func TestMutateDirtyRegion(t *testing.T) {
+	c := &Candidate{W: 100, H: 100, Polygons: []*Polygon{
+		{Points: []Point{{10, 20}, {30, 20}, {20, 40}}, Color: color.RGBA{255, 0, 0, 255}},
+	}}
+
+	dirty, layer := c.mutate([]int{MutationColor})
+	if expected := image.Rect(9, 19, 32, 42); dirty != expected {
+		t.Errorf("expected dirty region %v, got: %v", expected, dirty)
+	}
+	if layer != 0 {
+		t.Errorf("expected layer 0, got: %d", layer)
+	}
+
+	c.Polygons[0].Points = []Point{{0, 0}, {99, 0}, {0, 99}}
+	dirty, _ = c.mutate([]int{MutationAlpha})
+	if expected := image.Rect(0, 0, 100, 100); dirty != expected {
+		t.Errorf("expected dirty region clipped to %v, got: %v", expected, dirty)
+	}
+}
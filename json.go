@@ -0,0 +1,206 @@
+package polygen
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+)
+
+const (
+	// JSONFormat identifies polygen genomes in the "format" field of the JSON encoding, see WriteJSON.
+	JSONFormat = "polygen-genome"
+
+	// JSONVersion is the version of the JSON encoding written by WriteJSON. ReadJSON rejects later versions.
+	JSONVersion = 1
+)
+
+// jsonGenome is the JSON encoding of a Checkpoint, see WriteJSON.
+type jsonGenome struct {
+	Format                 string        `json:"format"`
+	Version                int           `json:"version"`
+	Width                  int           `json:"width"`
+	Height                 int           `json:"height"`
+	Background             string        `json:"background"`
+	Linear                 bool          `json:"linear,omitempty"`
+	Renderer               string        `json:"renderer,omitempty"`
+	Aliased                bool          `json:"aliased,omitempty"`
+	FillRule               string        `json:"fillRule,omitempty"`
+	Polygons               []jsonPolygon `json:"polygons"`
+	Metric                 string        `json:"metric,omitempty"`
+	Fitness                uint64        `json:"fitness,omitempty"`
+	Generation             int           `json:"generation,omitempty"`
+	GenerationsSinceChange int           `json:"generationsSinceChange,omitempty"`
+}
+
+type jsonPolygon struct {
+	Points [][2]int `json:"points"`
+	Color  [4]int   `json:"color"`
+}
+
+// WriteJSON writes cp to w as JSON, so that genomes can be read by tools other than polygen. The format (version 1)
+// is a single object:
+//
+//	{
+//	  "format": "polygen-genome",
+//	  "version": 1,
+//	  "width": 200,                  // size of the canvas, in pixels
+//	  "height": 200,
+//	  "background": "black",         // or "transparent"
+//	  "linear": true,                // optional: polygons are composited in linear light
+//	  "renderer": "scanline",        // optional: how the polygons are drawn, see ParseRenderer
+//	  "aliased": true,               // optional: edges are not anti-aliased
+//	  "fillRule": "nonzero",         // optional: "evenodd" or "nonzero", see ParseFillRule
+//	  "polygons": [                  // in z-order, from back to front
+//	    {"points": [[10, 20], [30, 20], [20, 40]], "color": [255, 128, 0, 200]},
+//	    ...
+//	  ],
+//	  "metric": "mae",               // optional: the progress of the run, see Checkpoint
+//	  "fitness": 1234567,
+//	  "generation": 5000,
+//	  "generationsSinceChange": 12
+//	}
+//
+// Points are integer pixel coordinates, with (0, 0) at the top left corner of the canvas. Colors are
+// non-premultiplied RGBA, from 0 to 255.
+func WriteJSON(w io.Writer, cp *Checkpoint) error {
+	c := cp.MostFit
+	g := jsonGenome{
+		Format:                 JSONFormat,
+		Version:                JSONVersion,
+		Width:                  c.W,
+		Height:                 c.H,
+		Background:             "black",
+		Linear:                 c.Linear,
+		Polygons:               []jsonPolygon{},
+		Metric:                 cp.Metric,
+		Fitness:                c.Fitness,
+		Generation:             cp.Generation,
+		GenerationsSinceChange: cp.GenerationsSinceChange,
+	}
+
+	if c.Transparent {
+		g.Background = "transparent"
+	}
+
+	if c.renderer != nil {
+		options := rendererOptions(c.renderer)
+		g.Renderer, g.Aliased, g.FillRule = c.renderer.Name(), options.Aliased, fillRuleName(options.FillRule)
+	}
+
+	for _, poly := range c.Polygons {
+		var p jsonPolygon
+		for _, pt := range poly.Points {
+			p.Points = append(p.Points, [2]int{pt.X, pt.Y})
+		}
+
+		nrgba := straightColor(poly.Color)
+		p.Color = [4]int{int(nrgba.R), int(nrgba.G), int(nrgba.B), int(nrgba.A)}
+
+		g.Polygons = append(g.Polygons, p)
+	}
+
+	return json.NewEncoder(w).Encode(g)
+}
+
+// ReadJSON reads a Checkpoint in the format written by WriteJSON.
+func ReadJSON(r io.Reader) (*Checkpoint, error) {
+	var g jsonGenome
+	if err := json.NewDecoder(r).Decode(&g); err != nil {
+		return nil, fmt.Errorf("error decoding JSON genome: %s", err)
+	}
+
+	if g.Format != JSONFormat {
+		return nil, fmt.Errorf("not a polygen genome: format is %q, expected %q", g.Format, JSONFormat)
+	}
+	if g.Version < 1 || g.Version > JSONVersion {
+		return nil, fmt.Errorf("unsupported genome version: %d", g.Version)
+	}
+	if g.Width <= 0 || g.Height <= 0 {
+		return nil, fmt.Errorf("invalid genome size: %dx%d", g.Width, g.Height)
+	}
+
+	c := &Candidate{W: g.Width, H: g.Height, Linear: g.Linear, Fitness: g.Fitness}
+
+	switch g.Background {
+	case "black":
+	case "transparent":
+		c.Transparent = true
+	default:
+		return nil, fmt.Errorf("invalid genome background: %q", g.Background)
+	}
+
+	for i, p := range g.Polygons {
+		if len(p.Points) < MinPolygonPoints {
+			return nil, fmt.Errorf("polygon %d has %d points, at least %d are required", i, len(p.Points), MinPolygonPoints)
+		}
+
+		poly := &Polygon{}
+		for _, pt := range p.Points {
+			if pt[0] < 0 || pt[0] >= g.Width || pt[1] < 0 || pt[1] >= g.Height {
+				return nil, fmt.Errorf("polygon %d: point %v is outside of the %dx%d canvas", i, pt, g.Width, g.Height)
+			}
+			poly.Points = append(poly.Points, Point{pt[0], pt[1]})
+		}
+
+		for _, v := range p.Color {
+			if v < 0 || v > 255 {
+				return nil, fmt.Errorf("polygon %d: invalid color %v", i, p.Color)
+			}
+		}
+		poly.Color = color.RGBAModel.Convert(color.NRGBA{uint8(p.Color[0]), uint8(p.Color[1]), uint8(p.Color[2]), uint8(p.Color[3])})
+
+		c.Polygons = append(c.Polygons, poly)
+	}
+
+	rule, err := ParseFillRule(g.FillRule)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{
+		Generation:             g.Generation,
+		GenerationsSinceChange: g.GenerationsSinceChange,
+		MostFit:                c,
+		Metric:                 g.Metric,
+		Renderer:               g.Renderer,
+		RenderOptions:          RenderOptions{Aliased: g.Aliased, FillRule: rule},
+	}
+
+	if err := cp.applyRenderer(); err != nil {
+		return nil, err
+	}
+
+	return cp, nil
+}
+
+// straightColor converts c to non-premultiplied RGBA. Unlike color.NRGBAModel, which rounds down, it picks values
+// that convert back to c exactly, where possible, so that genomes survive a round trip through JSON unchanged.
+func straightColor(c color.Color) color.NRGBA {
+	result := color.NRGBAModel.Convert(c).(color.NRGBA)
+	target := color.RGBAModel.Convert(c).(color.RGBA)
+
+	// translucent colors have several straight values for each premultiplied one; use the closest to the original
+	fix := func(v *uint8, want uint8) {
+		best, bestDist := int(*v), 256
+		for x := 0; x <= 255; x++ {
+			if dist := int(math.Abs(float64(x - int(*v)))); premultiply(uint8(x), result.A) == want && dist < bestDist {
+				best, bestDist = x, dist
+			}
+		}
+		*v = uint8(best)
+	}
+
+	fix(&result.R, target.R)
+	fix(&result.G, target.G)
+	fix(&result.B, target.B)
+
+	return result
+}
+
+// premultiply returns the straight color channel v premultiplied by alpha a, rounded as color.NRGBA.RGBA and
+// color.RGBAModel do.
+func premultiply(v, a uint8) uint8 {
+	return uint8(uint32(v) * 0x101 * (uint32(a) * 0x101) / 0xffff >> 8)
+}
@@ -0,0 +1,126 @@
+package polygen
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	c := randomCandidate(60, 40, 10)
+	c.Transparent = true
+	c.Fitness = 12345
+
+	cp := &Checkpoint{Generation: 500, GenerationsSinceChange: 7, MostFit: c, Metric: "mse"}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, cp); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(actual, cp) {
+		t.Errorf("expected %+v, got: %+v", cp, actual)
+	}
+}
+
+func TestReadJSONInvalid(t *testing.T) {
+	valid := `{"format": "polygen-genome", "version": 1, "width": 10, "height": 10, "background": "black",
+		"polygons": [{"points": [[0, 0], [9, 0], [0, 9]], "color": [255, 0, 0, 128]}]}`
+
+	if _, err := ReadJSON(strings.NewReader(valid)); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	for _, tc := range []struct{ old, new string }{
+		{`"polygen-genome"`, `"something-else"`},
+		{`"version": 1`, `"version": 2`},
+		{`"width": 10`, `"width": 0`},
+		{`"black"`, `"white"`},
+		{`[9, 0]`, `[10, 0]`},
+		{`[[0, 0], [9, 0], [0, 9]]`, `[[0, 0], [9, 0]]`},
+		{`128]`, `256]`},
+	} {
+		doc := strings.Replace(valid, tc.old, tc.new, 1)
+		if _, err := ReadJSON(strings.NewReader(doc)); err == nil {
+			t.Errorf("expected error replacing %s with %s", tc.old, tc.new)
+		}
+	}
+}
+
+func TestResumeFromJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "polygen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// SaveCheckpoint writes JSON based on the extension, and LoadCheckpoint detects it from the content
+	c := randomCandidate(20, 20, 5)
+	for _, name := range []string{"cp.json", "cp.tmp"} {
+		cpFile := filepath.Join(dir, name)
+		if err := SaveCheckpoint(cpFile, &Checkpoint{Generation: 42, MostFit: c}); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(cpFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isJSON := bytes.HasPrefix(b, []byte("{")); isJSON != (name == "cp.json") {
+			t.Errorf("%s: expected JSON: %v, got: %v", name, name == "cp.json", isJSON)
+		}
+
+		e, err := NewEvolver(image.NewRGBA(image.Rect(0, 0, 20, 20)), "", cpFile, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.generation != 42 || len(e.mostFit.Polygons) != 5 {
+			t.Errorf("%s: expected to resume at generation 42 with 5 polygons, got: %d, %d", name, e.generation, len(e.mostFit.Polygons))
+		}
+	}
+}
+
+func TestCheckpointRenderer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "polygen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := Scanline{RenderOptions{FillRule: FillNonZero}}
+	c := randomCandidate(20, 20, 5)
+	c.SetRenderer(r)
+	ref := image.NewRGBA(image.Rect(0, 0, 20, 20))
+
+	for _, name := range []string{"cp.json", "cp.tmp"} {
+		cpFile := filepath.Join(dir, name)
+		if err := SaveCheckpoint(cpFile, &Checkpoint{MostFit: c}); err != nil {
+			t.Fatal(err)
+		}
+
+		cp, err := LoadCheckpoint(cpFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp.MostFit.Renderer() != r || cp.Renderer != "scanline" || cp.RenderOptions != r.RenderOptions {
+			t.Errorf("%s: expected renderer %+v, got: %+v (%s, %+v)", name, r, cp.MostFit.Renderer(), cp.Renderer, cp.RenderOptions)
+		}
+
+		if _, err := NewEvolver(ref, "", cpFile, Options{}); err == nil {
+			t.Errorf("%s: expected error resuming a scanline checkpoint with draw2d", name)
+		}
+		if _, err := NewEvolver(ref, "", cpFile, Options{Renderer: r}); err != nil {
+			t.Errorf("%s: unexpected err: %s", name, err)
+		}
+	}
+}
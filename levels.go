@@ -0,0 +1,86 @@
+package polygen
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Level is one stage of a coarse-to-fine schedule: the Evolver runs for Generations generations
+// against a copy of the reference image that has been scaled by Scale.
+type Level struct {
+	Scale       float64
+	Generations int
+}
+
+// ParseLevels parses a schedule of the form "0.25:2000,0.5:3000", i.e. a comma-separated list of
+// scale:generations pairs. Evolution continues at full size once the schedule is exhausted.
+func ParseLevels(s string) ([]Level, error) {
+	var result []Level
+
+	if strings.TrimSpace(s) == "" {
+		return result, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("bad level %q, expected scale:generations", part)
+		}
+
+		scale, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || scale <= 0 || scale > 1 {
+			return nil, fmt.Errorf("bad scale in level %q, must be in (0, 1]", part)
+		}
+
+		gens, err := strconv.Atoi(fields[1])
+		if err != nil || gens <= 0 {
+			return nil, fmt.Errorf("bad generation count in level %q", part)
+		}
+
+		result = append(result, Level{Scale: scale, Generations: gens})
+	}
+
+	return result, nil
+}
+
+// levelAt returns the scale that applies at the given generation, and the generation at which that
+// level ends. The final (full size) level never ends, which is signaled by an end of -1.
+func levelAt(levels []Level, generation int) (scale float64, end int) {
+	end = 0
+	for _, l := range levels {
+		end += l.Generations
+		if generation < end {
+			return l.Scale, end
+		}
+	}
+
+	return 1, -1
+}
+
+// scaledSize returns the dimensions of a w x h image scaled by scale, never smaller than 1x1.
+func scaledSize(w, h int, scale float64) (int, int) {
+	sw := int(math.Round(float64(w) * scale))
+	sh := int(math.Round(float64(h) * scale))
+
+	if sw < 1 {
+		sw = 1
+	}
+	if sh < 1 {
+		sh = 1
+	}
+
+	return sw, sh
+}
+
+// ScaleImage returns a resampled copy of img with the given dimensions.
+func ScaleImage(img image.Image, w, h int) *image.RGBA {
+	result := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(result, result.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	return result
+}
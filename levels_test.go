@@ -0,0 +1,95 @@
+package polygen
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels("0.25:2000, 0.5:3000")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	expected := []Level{{0.25, 2000}, {0.5, 3000}}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Fatalf("expected %+v, got: %+v", expected, levels)
+	}
+
+	for _, bad := range []string{"0.25", "0:100", "1.5:100", "0.5:-1", "foo:bar"} {
+		if _, err := ParseLevels(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLevelAt(t *testing.T) {
+	levels := []Level{{0.25, 100}, {0.5, 200}}
+
+	var examples = []struct {
+		gen   int
+		scale float64
+		end   int
+	}{
+		{0, 0.25, 100},
+		{99, 0.25, 100},
+		{100, 0.5, 300},
+		{299, 0.5, 300},
+		{300, 1, -1},
+		{5000, 1, -1},
+	}
+
+	for _, tt := range examples {
+		scale, end := levelAt(levels, tt.gen)
+		if scale != tt.scale || end != tt.end {
+			t.Errorf("gen %d: wanted (%g, %d), got: (%g, %d)", tt.gen, tt.scale, tt.end, scale, end)
+		}
+	}
+}
+
+func TestCandidateScaledTo(t *testing.T) {
+	c := randomCandidate(200, 100, 10)
+	small := c.scaledTo(50, 25)
+
+	if small.W != 50 || small.H != 25 {
+		t.Fatalf("expected 50x25, got: %dx%d", small.W, small.H)
+	}
+
+	for _, poly := range small.Polygons {
+		for _, p := range poly.Points {
+			if p.X < 0 || p.X >= 50 || p.Y < 0 || p.Y >= 25 {
+				t.Fatalf("point out of bounds: %+v", p)
+			}
+		}
+	}
+
+	// the original should be untouched
+	if c.W != 200 || c.H != 100 {
+		t.Fatalf("scaledTo modified the original candidate")
+	}
+}
+
+func TestWeightMapLevels(t *testing.T) {
+	ref := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	weights := image.NewGray(ref.Bounds())
+
+	e, err := NewEvolver(ref, "", "", Options{Quiet: true, WeightMap: weights, Levels: []Level{{0.5, 10}}})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	e.mostFit = randomCandidate(100, 50, 5)
+	e.candidates[0] = e.mostFit
+
+	// the weight map should be scaled along with the reference image
+	e.enterLevel(0.5)
+	if len(e.weights) != 50*25 {
+		t.Fatalf("expected %d weights, got: %d", 50*25, len(e.weights))
+	}
+
+	e.enterLevel(1)
+	if len(e.weights) != 100*50 {
+		t.Fatalf("expected %d weights, got: %d", 100*50, len(e.weights))
+	}
+}
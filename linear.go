@@ -0,0 +1,202 @@
+package polygen
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/golang/freetype/raster"
+)
+
+// linearToSRGB maps 16-bit linear light values to 8-bit sRGB.
+var linearToSRGB [1 << 16]uint8
+
+func init() {
+	for i := range linearToSRGB {
+		linearToSRGB[i] = uint8(math.Round(encodeSRGB(float64(i)/0xffff) * 255))
+	}
+}
+
+// encodeSRGB applies the sRGB transfer function to a linear value in [0, 1]. See sRGBToLinear for the inverse.
+func encodeSRGB(c float64) float64 {
+	if c <= 0.0031308 {
+		return c * 12.92
+	}
+
+	return 1.055*math.Pow(c, 1/2.4) - 0.055
+}
+
+// decodeSRGB converts a 16-bit sRGB value to 16-bit linear light.
+func decodeSRGB(c uint32) uint32 {
+	v := float64(c) / 0xffff
+	if v <= 0.04045 {
+		v /= 12.92
+	} else {
+		v = math.Pow((v+0.055)/1.055, 2.4)
+	}
+
+	return uint32(math.Round(v * 0xffff))
+}
+
+// linearColor converts a (premultiplied sRGB) color to premultiplied 16-bit linear light.
+func linearColor(c color.Color) (r, g, b, a uint32) {
+	n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+	a = uint32(n.A)
+
+	return decodeSRGB(uint32(n.R)) * a / 0xffff, decodeSRGB(uint32(n.G)) * a / 0xffff, decodeSRGB(uint32(n.B)) * a / 0xffff, a
+}
+
+// linearPainter composites spans onto a premultiplied, linear light RGBA64 image, like raster.RGBAPainter does
+// for sRGB images.
+type linearPainter struct {
+	img            *image.RGBA64
+	cr, cg, cb, ca uint32
+}
+
+func (p *linearPainter) SetColor(c color.Color) {
+	p.cr, p.cg, p.cb, p.ca = linearColor(c)
+}
+
+func (p *linearPainter) Paint(ss []raster.Span, done bool) {
+	const m = 1<<16 - 1
+	b := p.img.Bounds()
+
+	for _, s := range ss {
+		if s.Y < b.Min.Y {
+			continue
+		}
+		if s.Y >= b.Max.Y {
+			return
+		}
+		if s.X0 < b.Min.X {
+			s.X0 = b.Min.X
+		}
+		if s.X1 > b.Max.X {
+			s.X1 = b.Max.X
+		}
+		if s.X0 >= s.X1 {
+			continue
+		}
+
+		ma := uint64(s.Alpha)
+		a := m - uint64(p.ca)*ma/m
+		src := [4]uint64{uint64(p.cr) * ma, uint64(p.cg) * ma, uint64(p.cb) * ma, uint64(p.ca) * ma}
+
+		i0 := p.img.PixOffset(s.X0, s.Y)
+		i1 := i0 + (s.X1-s.X0)*8
+		for i := i0; i < i1; i += 8 {
+			for j := 0; j < 4; j++ {
+				pix := p.img.Pix[i+j*2 : i+j*2+2]
+				d := uint64(pix[0])<<8 | uint64(pix[1])
+				v := (d*a + src[j]) / m
+				pix[0], pix[1] = uint8(v>>8), uint8(v)
+			}
+		}
+	}
+}
+
+// renderLinear renders the Candidate by compositing its polygons in linear light, at 16 bits per channel, so that
+// translucent overlaps blend as they would physically. The linear render is kept for comparison with the reference
+// image (see LinearCompare), and converted to sRGB for the Candidate's regular image.
+func (cd *Candidate) renderLinear() {
+	cd.linear = image.NewRGBA64(cd.bounds())
+	canvas := cd.Renderer().NewCanvas(cd.linear, &linearPainter{img: cd.linear})
+	cd.draw(canvas, image.Point{}, cd.linear.Bounds(), 0, len(cd.Polygons))
+
+	cd.img = linearToRGBA(cd.linear)
+}
+
+// rgbaToLinear converts an sRGB image to premultiplied, 16-bit linear light.
+func rgbaToLinear(img *image.RGBA) *image.RGBA64 {
+	result := image.NewRGBA64(img.Rect)
+
+	for i := 0; i < len(img.Pix)/4; i++ {
+		p := img.Pix[i*4 : i*4+4]
+		r, g, b, a := linearColor(color.RGBA{p[0], p[1], p[2], p[3]})
+
+		q := result.Pix[i*8 : i*8+8]
+		q[0], q[1] = uint8(r>>8), uint8(r)
+		q[2], q[3] = uint8(g>>8), uint8(g)
+		q[4], q[5] = uint8(b>>8), uint8(b)
+		q[6], q[7] = uint8(a>>8), uint8(a)
+	}
+
+	return result
+}
+
+// linearToRGBA converts a premultiplied, linear light image back to 8-bit sRGB.
+func linearToRGBA(img *image.RGBA64) *image.RGBA {
+	result := image.NewRGBA(img.Rect)
+
+	for i := 0; i < len(result.Pix)/4; i++ {
+		q := img.Pix[i*8 : i*8+8]
+		a := uint32(q[6])<<8 | uint32(q[7])
+		if a == 0 {
+			continue
+		}
+
+		p := result.Pix[i*4 : i*4+4]
+		p[3] = uint8(a >> 8)
+
+		for j := 0; j < 3; j++ {
+			// unpremultiply, encode, and premultiply again in sRGB, as image.RGBA expects
+			c := (uint32(q[j*2])<<8 | uint32(q[j*2+1])) * 0xffff / a
+			if c > 0xffff {
+				c = 0xffff
+			}
+			p[j] = uint8(uint32(linearToSRGB[c]) * uint32(p[3]) / 0xff)
+		}
+	}
+
+	return result
+}
+
+// LinearCompare is like FastCompare (or FastCompareWeighted, if weights is non-nil), but for linear light images
+// with 16 bits per channel.
+func LinearCompare(img1, img2 *image.RGBA64, weights []uint8) (uint64, error) {
+	if img1.Bounds() != img2.Bounds() {
+		return 0, fmt.Errorf("image bounds not equal: %+v, %+v", img1.Bounds(), img2.Bounds())
+	}
+
+	if weights != nil && len(weights)*8 != len(img1.Pix) {
+		return 0, fmt.Errorf("weights length %d does not match image size %+v", len(weights), img1.Bounds())
+	}
+
+	accumError := uint64(0)
+
+	for i := 0; i < len(img1.Pix); i += 8 {
+		d := uint64(0)
+		for j := i; j < i+8; j += 2 {
+			v1 := int(img1.Pix[j])<<8 | int(img1.Pix[j+1])
+			v2 := int(img2.Pix[j])<<8 | int(img2.Pix[j+1])
+			if v1 > v2 {
+				d += uint64(v1 - v2)
+			} else {
+				d += uint64(v2 - v1)
+			}
+		}
+
+		if weights != nil {
+			d *= uint64(weights[i/8])
+		}
+		accumError += d
+	}
+
+	return accumError / rawScale(weights), nil
+}
+
+// evaluateLinear sets the fitness of c, which must already have been rendered in linear light.
+func (e *Evolver) evaluateLinear(c *Candidate) {
+	diff, err := LinearCompare(e.refLinear, c.linear, e.weights)
+	if err != nil {
+		log.Fatalf("error comparing images: %s", err)
+	}
+
+	if e.options.EdgeWeight > 0 {
+		diff += uint64(e.options.EdgeWeight * 0x101 * float64(edgeError(e.refEdges, sobel(c.img), e.weights)))
+	}
+
+	c.Fitness = diff
+}
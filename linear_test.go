@@ -0,0 +1,69 @@
+package polygen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestLinearRoundTrip(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(i/4), uint8(rand.Intn(256)), uint8(rand.Intn(256)), 255
+	}
+
+	if result := linearToRGBA(rgbaToLinear(img)); !bytes.Equal(result.Pix, img.Pix) {
+		t.Errorf("expected opaque colors to survive conversion to linear light and back")
+	}
+}
+
+func TestRenderLinear(t *testing.T) {
+	c := &Candidate{W: 10, H: 10, Polygons: []*Polygon{
+		{Points: []Point{{0, 0}, {9, 0}, {9, 9}, {0, 9}}, Color: color.RGBAModel.Convert(color.NRGBA{255, 255, 255, 128})},
+	}}
+
+	// half white over black is a middle gray in sRGB, but half as bright in linear light, which is much lighter
+	// when encoded as sRGB
+	c.renderImage()
+	if r := c.img.RGBAAt(5, 5).R; r < 127 || r > 129 {
+		t.Errorf("expected sRGB blend of about 128, got: %d", r)
+	}
+
+	c.Linear = true
+	c.renderImage()
+	if r := c.img.RGBAAt(5, 5).R; r < 186 || r > 189 {
+		t.Errorf("expected linear blend of about 188, got: %d", r)
+	}
+	if c.linear == nil {
+		t.Fatalf("expected the linear render to be kept")
+	}
+}
+
+func TestLinearEvolver(t *testing.T) {
+	ref := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for i := range ref.Pix {
+		ref.Pix[i] = uint8(rand.Intn(256))
+	}
+
+	if _, err := NewEvolver(ref, "", "", Options{Linear: true, Metric: MSE{}}); err == nil {
+		t.Errorf("expected an error for linear mode with the mse metric")
+	}
+
+	e, err := NewEvolver(ref, "", "", Options{Quiet: true, Linear: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Run(20, 5, nil)
+
+	if !e.mostFit.Linear {
+		t.Fatalf("expected the most fit candidate to be rendered in linear light")
+	}
+
+	expected, _ := LinearCompare(rgbaToLinear(ref), e.mostFit.linear, nil)
+	if e.mostFit.Fitness != expected {
+		t.Errorf("expected fitness %d, got: %d", expected, e.mostFit.Fitness)
+	}
+}
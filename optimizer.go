@@ -0,0 +1,267 @@
+package polygen
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// Objective scores a genome vector; lower is better. It must be safe to call from multiple goroutines.
+type Objective func(x []float64) float64
+
+// Optimizer searches for the genome vector that minimizes an Objective, which an Evolver uses to vary the
+// continuous parameters of a Candidate (see genome()). The exception is the default HillClimber, which the
+// Evolver runs on Candidates directly, so that it can also change their structure.
+type Optimizer interface {
+	// Optimize starts the search from x0, with each gene i bounded by [lower[i], upper[i]], and returns the
+	// best vector found after at most evals calls to f.
+	Optimize(x0, lower, upper []float64, f Objective, evals int) []float64
+}
+
+// ParseOptimizer returns the Optimizer with the given name: "hill" (or "") for the default HillClimber, "de" for
+// differential evolution, or "cmaes" for CMA-ES.
+func ParseOptimizer(name string) (Optimizer, error) {
+	switch name {
+	case "", "hill":
+		return HillClimber{}, nil
+	case "de":
+		return &DifferentialEvolution{}, nil
+	case "cmaes":
+		return &CMAES{}, nil
+	default:
+		return nil, fmt.Errorf("unknown optimizer: %q", name)
+	}
+}
+
+// evaluateAll computes f for each of xs in parallel.
+func evaluateAll(xs [][]float64, f Objective) []float64 {
+	result := make([]float64, len(xs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	for i := range xs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			result[i] = f(xs[i])
+			<-sem
+		}(i)
+	}
+
+	wg.Wait()
+	return result
+}
+
+// normalizer maps genes between their natural bounds and the unit interval, so that the optimizers can
+// treat coordinates and color channels alike.
+type normalizer struct {
+	lower, upper []float64
+}
+
+func (n normalizer) toUnit(x []float64) []float64 {
+	result := make([]float64, len(x))
+	for i := range x {
+		if n.upper[i] > n.lower[i] {
+			result[i] = (x[i] - n.lower[i]) / (n.upper[i] - n.lower[i])
+		}
+	}
+
+	return result
+}
+
+func (n normalizer) fromUnit(u []float64) []float64 {
+	result := make([]float64, len(u))
+	for i := range u {
+		result[i] = n.lower[i] + clampUnit(u[i])*(n.upper[i]-n.lower[i])
+	}
+
+	return result
+}
+
+func (n normalizer) objective(f Objective) Objective {
+	return func(u []float64) float64 {
+		return f(n.fromUnit(u))
+	}
+}
+
+func clampUnit(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+
+	return v
+}
+
+// HillClimber repeatedly mutates copies of the best solution so far, and keeps the best of them. It is the
+// default Optimizer. An Evolver runs it on Candidates rather than genome vectors: each child gets
+// MutationsPerIteration random mutations (see Mutations), which may also add and remove points or reorder
+// polygons, and is evaluated incrementally. Optimize is the same search over a genome vector, where each child
+// moves a single gene.
+type HillClimber struct{}
+
+// hillClimbStep is the largest step of a gene in HillClimber.Optimize, as a fraction of its range. Smaller steps
+// are as likely, to allow fine tuning.
+const hillClimbStep = 0.1
+
+func (HillClimber) Optimize(x0, lower, upper []float64, f Objective, evals int) []float64 {
+	if evals < 1 {
+		return x0
+	}
+
+	norm := normalizer{lower, upper}
+	obj := norm.objective(f)
+
+	best := norm.toUnit(x0)
+	bestScore := obj(best)
+	evals--
+
+	for evals > 0 {
+		children := make([][]float64, PopulationCount-1)
+		if len(children) > evals {
+			children = children[:evals]
+		}
+
+		for i := range children {
+			child := append([]float64(nil), best...)
+			j := rand.Intn(len(child))
+			child[j] = clampUnit(child[j] + rand.NormFloat64()*hillClimbStep/float64(int(1)<<uint(rand.Intn(8))))
+			children[i] = child
+		}
+
+		scores := evaluateAll(children, obj)
+		evals -= len(children)
+
+		for i := range children {
+			if scores[i] < bestScore {
+				best, bestScore = children[i], scores[i]
+			}
+		}
+	}
+
+	return norm.fromUnit(best)
+}
+
+// isHillClimber reports whether o is the HillClimber (or nil, which means the same).
+func isHillClimber(o Optimizer) bool {
+	switch o.(type) {
+	case nil, HillClimber, *HillClimber:
+		return true
+	default:
+		return false
+	}
+}
+
+// DifferentialEvolution implements the classic DE/rand/1/bin strategy. The initial population is
+// scattered around x0 rather than uniformly, so that it can refine an existing Candidate.
+type DifferentialEvolution struct {
+	PopulationSize int     // defaults to 20
+	F              float64 // differential weight, defaults to 0.5
+	CR             float64 // crossover probability, defaults to 0.9
+	InitialSpread  float64 // std dev of the initial population around x0, as a fraction of each gene's range. Defaults to 0.02
+}
+
+func (de *DifferentialEvolution) Optimize(x0, lower, upper []float64, f Objective, evals int) []float64 {
+	np, fw, cr, spread := de.PopulationSize, de.F, de.CR, de.InitialSpread
+	if np < 4 {
+		np = 20
+	}
+	if fw == 0 {
+		fw = 0.5
+	}
+	if cr == 0 {
+		cr = 0.9
+	}
+	if spread == 0 {
+		spread = 0.02
+	}
+
+	// the initial population alone must not exceed the budget
+	if np > evals {
+		np = evals
+	}
+	if np < 1 {
+		return x0
+	}
+
+	norm := normalizer{lower, upper}
+	obj := norm.objective(f)
+	n := len(x0)
+
+	pop := make([][]float64, np)
+	pop[0] = norm.toUnit(x0)
+	for i := 1; i < np; i++ {
+		pop[i] = make([]float64, n)
+		for j := range pop[i] {
+			pop[i][j] = clampUnit(pop[0][j] + rand.NormFloat64()*spread)
+		}
+	}
+
+	scores := evaluateAll(pop, obj)
+	evals -= np
+
+	for evals >= np {
+		trials := make([][]float64, np)
+		for i := range pop {
+			a, b, c := distinctIndexes(np, i)
+			jRand := rand.Intn(n)
+
+			trial := make([]float64, n)
+			for j := 0; j < n; j++ {
+				if j == jRand || rand.Float64() < cr {
+					trial[j] = clampUnit(pop[a][j] + fw*(pop[b][j]-pop[c][j]))
+				} else {
+					trial[j] = pop[i][j]
+				}
+			}
+			trials[i] = trial
+		}
+
+		trialScores := evaluateAll(trials, obj)
+		evals -= np
+
+		for i := range pop {
+			if trialScores[i] <= scores[i] {
+				pop[i], scores[i] = trials[i], trialScores[i]
+			}
+		}
+	}
+
+	best := 0
+	for i := range scores {
+		if scores[i] < scores[best] {
+			best = i
+		}
+	}
+
+	return norm.fromUnit(pop[best])
+}
+
+// distinctIndexes returns three different random indexes in [0, n), none of which are equal to exclude.
+func distinctIndexes(n, exclude int) (int, int, int) {
+	pick := func(used ...int) int {
+		for {
+			r := rand.Intn(n)
+			ok := true
+			for _, u := range used {
+				if r == u {
+					ok = false
+				}
+			}
+			if ok {
+				return r
+			}
+		}
+	}
+
+	a := pick(exclude)
+	b := pick(exclude, a)
+	c := pick(exclude, a, b)
+
+	return a, b, c
+}
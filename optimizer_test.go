@@ -0,0 +1,144 @@
+package polygen
+
+import (
+	"image/color"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// sphere has its minimum of 0 at (0.3, 0.3, ...)
+func sphere(x []float64) float64 {
+	sum := 0.0
+	for _, v := range x {
+		sum += (v - 0.3) * (v - 0.3)
+	}
+
+	return sum
+}
+
+func bounds(n int) (x0, lower, upper []float64) {
+	for i := 0; i < n; i++ {
+		x0 = append(x0, 0.8)
+		lower = append(lower, -1)
+		upper = append(upper, 1)
+	}
+
+	return
+}
+
+func TestOptimizersMinimizeSphere(t *testing.T) {
+	optimizers := map[string]Optimizer{
+		"hill":  HillClimber{},
+		"de":    &DifferentialEvolution{PopulationSize: 40, InitialSpread: 0.3},
+		"cmaes": &CMAES{Sigma: 0.2},
+	}
+
+	for name, opt := range optimizers {
+		x0, lower, upper := bounds(10)
+		best := opt.Optimize(x0, lower, upper, sphere, 20000)
+
+		if f := sphere(best); f > 1e-3 || math.IsNaN(f) {
+			t.Errorf("%s: expected to converge near 0, got: %g at %v", name, f, best)
+		}
+
+		for i := range best {
+			if best[i] < lower[i] || best[i] > upper[i] {
+				t.Errorf("%s: gene %d out of bounds: %g", name, i, best[i])
+			}
+		}
+	}
+}
+
+func TestGenomeRoundTrip(t *testing.T) {
+	c := randomCandidate(100, 100, 10)
+
+	// translucent colors can lose precision when premultiplied, so stick to opaque ones here
+	for _, poly := range c.Polygons {
+		nrgba := color.NRGBAModel.Convert(poly.Color).(color.NRGBA)
+		nrgba.A = 255
+		poly.Color = color.RGBAModel.Convert(nrgba)
+	}
+
+	x, lower, upper := c.genome()
+	if len(x) != len(lower) || len(x) != len(upper) {
+		t.Fatalf("genome and bounds lengths differ: %d, %d, %d", len(x), len(lower), len(upper))
+	}
+
+	c2 := c.withGenome(x)
+
+	if !reflect.DeepEqual(c, c2) {
+		t.Fatalf("c != c2: %+v, %+v", c, c2)
+	}
+}
+
+func TestOptimizersRespectBudget(t *testing.T) {
+	optimizers := map[string]Optimizer{
+		"hill":  HillClimber{},
+		"de":    &DifferentialEvolution{},
+		"cmaes": &CMAES{},
+	}
+
+	for name, opt := range optimizers {
+		for _, budget := range []int{0, 1, 3, 15, 45} {
+			var mu sync.Mutex
+			evals := 0
+			f := func(x []float64) float64 {
+				mu.Lock()
+				evals++
+				mu.Unlock()
+				return sphere(x)
+			}
+
+			x0, lower, upper := bounds(5)
+			opt.Optimize(x0, lower, upper, f, budget)
+
+			if evals > budget {
+				t.Errorf("%s: %d evaluations for a budget of %d", name, evals, budget)
+			}
+		}
+	}
+}
+
+func TestParseOptimizer(t *testing.T) {
+	for _, name := range []string{"", "hill"} {
+		if opt, err := ParseOptimizer(name); opt != (HillClimber{}) || err != nil {
+			t.Errorf("expected the hill climber for %q", name)
+		}
+	}
+
+	if _, err := ParseOptimizer("bogus"); err == nil {
+		t.Errorf("expected error for unknown optimizer")
+	}
+}
+
+// benchmarkOptimizer evolves a small copy of the mona lisa for a fixed number of generations, and reports the
+// resulting fitness so that the search strategies can be compared with e.g.:
+//
+//	go test -run XXX -bench Optimizer -benchtime 1x
+func benchmarkOptimizer(b *testing.B, opt Optimizer) {
+	ref := ScaleImage(MustReadImage("images/mona_lisa.jpg"), 50, 50)
+
+	for i := 0; i < b.N; i++ {
+		e, err := NewEvolver(ref, "", "", Options{Quiet: true, Optimizer: opt})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		e.Run(500, 20, nil)
+		b.ReportMetric(float64(e.mostFit.Fitness), "fitness")
+	}
+}
+
+func BenchmarkOptimizerHillClimber(b *testing.B) {
+	benchmarkOptimizer(b, HillClimber{})
+}
+
+func BenchmarkOptimizerDifferentialEvolution(b *testing.B) {
+	benchmarkOptimizer(b, &DifferentialEvolution{})
+}
+
+func BenchmarkOptimizerCMAES(b *testing.B) {
+	benchmarkOptimizer(b, &CMAES{})
+}
@@ -0,0 +1,90 @@
+package polygen
+
+import (
+	"image/color"
+	"log"
+	"time"
+)
+
+// polish makes a deterministic coordinate-descent pass over the most fit candidate: each vertex coordinate
+// and each color channel of every polygon is nudged by +1 and -1, and every move that improves fitness is
+// kept (and repeated, for as long as it keeps improving). Random mutation rarely finds these small
+// improvements once evolution has stagnated.
+func (e *Evolver) polish() {
+	start := time.Now()
+	before := e.mostFit.Fitness
+	best := e.mostFit
+
+	// try applies move to a copy of best, and keeps the copy if it is an improvement. The move
+	// returns false if it would not change anything.
+	try := func(move func(c *Candidate) bool) bool {
+		cand := best.copyOf()
+		if !move(cand) {
+			return false
+		}
+
+		e.renderAndEvaluate(cand)
+		if cand.Fitness < best.Fitness {
+			best = cand
+			return true
+		}
+
+		return false
+	}
+
+	for pi := range best.Polygons {
+		for vi := range best.Polygons[pi].Points {
+			for _, delta := range []Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+				delta := delta
+				for try(func(c *Candidate) bool {
+					p := &c.Polygons[pi].Points[vi]
+					x, y := p.X+delta.X, p.Y+delta.Y
+					if x < 0 || x >= c.W || y < 0 || y >= c.H {
+						return false
+					}
+					p.X, p.Y = x, y
+					return true
+				}) {
+				}
+			}
+		}
+
+		for channel := 0; channel < 4; channel++ {
+			for _, delta := range []int{1, -1} {
+				channel, delta := channel, delta
+				for try(func(c *Candidate) bool {
+					poly := c.Polygons[pi]
+					nudged, ok := nudgeChannel(poly.Color, channel, delta)
+					poly.Color = nudged
+					return ok
+				}) {
+				}
+			}
+		}
+	}
+
+	if best != e.mostFit {
+		e.mostFit = best
+		e.candidates[0] = best
+		e.generationsSinceChange = 0
+	}
+	e.polished = best
+
+	log.Printf("polish pass at gen %d: fitness %d -> %d (gained %d) in %s", e.generation, before, best.Fitness, before-best.Fitness, time.Since(start))
+}
+
+// nudgeChannel adds delta to one of the non-premultiplied R, G, B or A channels of c (selected by channel,
+// 0-3). Returns false if the result would be out of range, or identical to c once premultiplied.
+func nudgeChannel(c color.Color, channel, delta int) (color.Color, bool) {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	values := []*uint8{&nrgba.R, &nrgba.G, &nrgba.B, &nrgba.A}
+
+	v := int(*values[channel]) + delta
+	if v < 0 || v > 255 {
+		return c, false
+	}
+	*values[channel] = uint8(v)
+
+	result := color.RGBAModel.Convert(nrgba)
+	return result, result != color.RGBAModel.Convert(c)
+}
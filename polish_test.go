@@ -0,0 +1,61 @@
+package polygen
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPolish(t *testing.T) {
+	target := randomCandidate(50, 50, 5)
+	target.renderImage()
+
+	e, err := NewEvolver(target.img, "", "", Options{Quiet: true})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	// start from a slightly displaced copy of the target, which polish should be able to improve on
+	e.mostFit = target.copyOf()
+	for _, poly := range e.mostFit.Polygons {
+		poly.Points[0].mutateNearby(50, 50)
+	}
+	e.candidates[0] = e.mostFit
+	e.renderAndEvaluate(e.mostFit)
+	before := e.mostFit.Fitness
+
+	e.polish()
+
+	if e.mostFit.Fitness > before {
+		t.Fatalf("polish made fitness worse: %d -> %d", before, e.mostFit.Fitness)
+	}
+
+	if before > 0 && e.mostFit.Fitness == before {
+		t.Fatalf("expected polish to improve on %d", before)
+	}
+
+	if e.polished != e.mostFit {
+		t.Errorf("expected the polished candidate to be recorded, so that it is not polished again")
+	}
+}
+
+func TestNudgeChannel(t *testing.T) {
+	c := color.NRGBA{R: 10, G: 255, B: 0, A: 255}
+
+	nudged, ok := nudgeChannel(c, 0, 1)
+	if !ok || nudged != color.RGBAModel.Convert(color.NRGBA{R: 11, G: 255, B: 0, A: 255}) {
+		t.Errorf("expected red to be nudged, got: %v, %v", nudged, ok)
+	}
+
+	if _, ok := nudgeChannel(c, 1, 1); ok {
+		t.Errorf("expected green to be out of range")
+	}
+
+	if _, ok := nudgeChannel(c, 2, -1); ok {
+		t.Errorf("expected blue to be out of range")
+	}
+
+	// fully transparent colors premultiply to zero, so nudging them changes nothing
+	if _, ok := nudgeChannel(color.NRGBA{R: 10, A: 0}, 0, 1); ok {
+		t.Errorf("expected no change for a transparent color")
+	}
+}
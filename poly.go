@@ -0,0 +1,388 @@
+package polygen
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"math/bits"
+	"os"
+)
+
+const (
+	// PolyMagic starts every .poly file, see EncodePoly.
+	PolyMagic = "POLY"
+
+	// PolyVersion is the version of the .poly format written by EncodePoly.
+	PolyVersion = 1
+
+	// MaxPolySize is the largest width or height of a .poly image.
+	MaxPolySize = 1 << 15
+
+	// MaxPolyPixels is the largest number of pixels (width x height) in a .poly image. Decoding renders the
+	// image, so this limits the memory that a small (possibly corrupt or malicious) file can allocate.
+	MaxPolyPixels = 1 << 26
+)
+
+// flags in the .poly header
+const (
+	polyTransparent = 1 << iota
+	polyLinear
+	polyAliased
+	polyNonZero
+	polyScanline // drawn with the Scanline renderer
+	polyVector   // drawn with the Vector renderer
+
+	// polyFlags are all of the flags above; files with any other flags set are rejected
+	polyFlags = 1<<iota - 1
+)
+
+func init() {
+	image.RegisterFormat("poly", PolyMagic, decodePolyImage, decodePolyConfig)
+}
+
+// polyHeader is the fixed part of a .poly file.
+type polyHeader struct {
+	flags     byte
+	w, h      int
+	polygons  int
+	countBits int // bits for the number of points of each polygon, minus MinPolygonPoints
+	xBits     int // bits for each x coordinate
+	yBits     int // bits for each y coordinate
+	colorBits int // bits for each color channel
+}
+
+// EncodePoly writes the Candidate to w in the compact binary .poly format, at full precision (see
+// EncodePolyQuantized). Importing this package registers a decoder for the format with image.Decode, which
+// renders the polygons into an image. A .poly file is:
+//
+//	"POLY"                               magic
+//	version                              1 byte, currently 1
+//	flags                                1 byte: 1 = transparent, 2 = linear, 4 = aliased, 8 = non-zero fill rule,
+//	                                     16 = scanline renderer, 32 = vector renderer (draw2d if neither)
+//	width, height, polygons              uvarints
+//	countBits, xBits, yBits, colorBits   1 byte each
+//
+// followed by the polygons in z-order, packed into a bit stream (most significant bit first): the number of
+// points minus MinPolygonPoints in countBits, each point's x and y in xBits and yBits, and the color's
+// non-premultiplied R, G, B and A in colorBits each. If xBits (or yBits) is too small to hold every coordinate
+// of the image, the coordinates are quantized to 2^xBits evenly spaced positions across it, and if colorBits is
+// less than 8, the colors are quantized to 2^colorBits evenly spaced values, see Quantization.
+func EncodePoly(w io.Writer, cd *Candidate) error {
+	return EncodePolyQuantized(w, cd, Quantization{})
+}
+
+// EncodePolyQuantized writes the Candidate to w in the .poly format, with the precision given by q.
+func EncodePolyQuantized(w io.Writer, cd *Candidate, q Quantization) error {
+	h, err := newPolyHeader(cd, q)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(PolyMagic)
+	bw.WriteByte(PolyVersion)
+	bw.WriteByte(h.flags)
+
+	var buf [binary.MaxVarintLen64]byte
+	for _, v := range []int{h.w, h.h, h.polygons} {
+		n := binary.PutUvarint(buf[:], uint64(v))
+		bw.Write(buf[:n])
+	}
+	bw.Write([]byte{byte(h.countBits), byte(h.xBits), byte(h.yBits), byte(h.colorBits)})
+
+	var stream bitWriter
+	for _, poly := range cd.Polygons {
+		stream.write(uint64(len(poly.Points)-MinPolygonPoints), h.countBits)
+
+		for _, p := range poly.Points {
+			stream.write(uint64(quantizeCoord(p.X, cd.W, h.xBits)), h.xBits)
+			stream.write(uint64(quantizeCoord(p.Y, cd.H, h.yBits)), h.yBits)
+		}
+
+		for _, v := range quantizeColor(poly.Color, h.colorBits) {
+			stream.write(uint64(v), h.colorBits)
+		}
+	}
+	bw.Write(stream.bytes())
+
+	return bw.Flush()
+}
+
+// newPolyHeader returns the header for encoding the Candidate with q.
+func newPolyHeader(cd *Candidate, q Quantization) (polyHeader, error) {
+	h := polyHeader{
+		w:         cd.W,
+		h:         cd.H,
+		polygons:  len(cd.Polygons),
+		xBits:     q.coordBits(cd.W),
+		yBits:     q.coordBits(cd.H),
+		colorBits: q.colorBits(),
+	}
+
+	if cd.W <= 0 || cd.H <= 0 || cd.W > MaxPolySize || cd.H > MaxPolySize || cd.W*cd.H > MaxPolyPixels {
+		return h, fmt.Errorf("invalid size for a .poly image: %dx%d", cd.W, cd.H)
+	}
+
+	maxCount := 0
+	for _, poly := range cd.Polygons {
+		if len(poly.Points) < MinPolygonPoints {
+			return h, fmt.Errorf("polygon has %d points, at least %d are required", len(poly.Points), MinPolygonPoints)
+		}
+		if n := len(poly.Points) - MinPolygonPoints; n > maxCount {
+			maxCount = n
+		}
+	}
+	h.countBits = bits.Len(uint(maxCount))
+
+	if cd.Transparent {
+		h.flags |= polyTransparent
+	}
+	if cd.Linear {
+		h.flags |= polyLinear
+	}
+	options := effectiveRenderOptions(cd.Renderer())
+	if options.Aliased {
+		h.flags |= polyAliased
+	}
+	if options.FillRule == FillNonZero {
+		h.flags |= polyNonZero
+	}
+	switch cd.Renderer().(type) {
+	case Scanline:
+		h.flags |= polyScanline
+	case Vector:
+		h.flags |= polyVector
+	}
+
+	return h, nil
+}
+
+// size returns the number of bytes taken by a Candidate with this header and the given total number of points.
+func (h polyHeader) size(points int) int {
+	var buf [binary.MaxVarintLen64]byte
+
+	result := len(PolyMagic) + 2 + 4
+	for _, v := range []int{h.w, h.h, h.polygons} {
+		result += binary.PutUvarint(buf[:], uint64(v))
+	}
+
+	streamBits := h.polygons*(h.countBits+4*h.colorBits) + points*(h.xBits+h.yBits)
+
+	return result + (streamBits+7)/8
+}
+
+// DecodePoly reads a Candidate in the .poly format, see EncodePoly.
+func DecodePoly(r io.Reader) (*Candidate, error) {
+	br := bufio.NewReader(r)
+
+	h, err := readPolyHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Candidate{W: h.w, H: h.h, Transparent: h.flags&polyTransparent != 0, Linear: h.flags&polyLinear != 0}
+
+	options := RenderOptions{Aliased: h.flags&polyAliased != 0}
+	if h.flags&polyNonZero != 0 {
+		options.FillRule = FillNonZero
+	}
+	switch {
+	case h.flags&polyScanline != 0:
+		result.renderer = Scanline{options}
+	case h.flags&polyVector != 0:
+		result.renderer = Vector{options}
+	case options != (RenderOptions{}):
+		result.renderer = Draw2D{options}
+	}
+
+	stream := bitReader{data: data}
+	for i := 0; i < h.polygons; i++ {
+		poly := &Polygon{}
+
+		n := int(stream.read(h.countBits)) + MinPolygonPoints
+		for j := 0; j < n; j++ {
+			x := expandCoord(int(stream.read(h.xBits)), h.w, h.xBits)
+			y := expandCoord(int(stream.read(h.yBits)), h.h, h.yBits)
+			if x < 0 || y < 0 || x >= h.w || y >= h.h {
+				return nil, fmt.Errorf("polygon %d: point (%d, %d) is outside of the %dx%d canvas", i, x, y, h.w, h.h)
+			}
+			poly.Points = append(poly.Points, Point{x, y})
+		}
+
+		var c [4]uint8
+		for j := range c {
+			c[j] = uint8(stream.read(h.colorBits))
+		}
+		poly.Color = expandColor(c, h.colorBits)
+
+		if stream.overrun {
+			return nil, fmt.Errorf("truncated .poly data in polygon %d", i)
+		}
+		result.Polygons = append(result.Polygons, poly)
+	}
+
+	return result, nil
+}
+
+// SavePoly writes the Candidate to the given file in the .poly format, with the precision given by q (see
+// EncodePolyQuantized).
+func SavePoly(file string, cd *Candidate, q Quantization) error {
+	outfile, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	if err := EncodePolyQuantized(outfile, cd, q); err != nil {
+		outfile.Close()
+		return err
+	}
+
+	return outfile.Close()
+}
+
+// PolySize returns the size in bytes of the Candidate in the .poly format, with the precision given by q.
+func PolySize(cd *Candidate, q Quantization) (int, error) {
+	h, err := newPolyHeader(cd, q)
+	if err != nil {
+		return 0, err
+	}
+
+	return h.size(cd.vertexCount()), nil
+}
+
+func readPolyHeader(r *bufio.Reader) (polyHeader, error) {
+	var h polyHeader
+
+	magic := make([]byte, len(PolyMagic)+2)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return h, fmt.Errorf("error reading .poly header: %s", err)
+	}
+	if string(magic[:len(PolyMagic)]) != PolyMagic {
+		return h, errors.New("not a .poly image")
+	}
+	if version := magic[len(PolyMagic)]; version != PolyVersion {
+		return h, fmt.Errorf("unsupported .poly version: %d", version)
+	}
+	h.flags = magic[len(PolyMagic)+1]
+	if h.flags&^polyFlags != 0 || h.flags&polyScanline != 0 && h.flags&polyVector != 0 {
+		return h, fmt.Errorf("invalid .poly flags: %#x", h.flags)
+	}
+
+	for _, v := range []*int{&h.w, &h.h, &h.polygons} {
+		u, err := binary.ReadUvarint(r)
+		if err != nil {
+			return h, fmt.Errorf("error reading .poly header: %s", err)
+		}
+		if u > MaxPolySize*MaxPolySize {
+			return h, fmt.Errorf("invalid .poly header value: %d", u)
+		}
+		*v = int(u)
+	}
+
+	if h.w <= 0 || h.h <= 0 || h.w > MaxPolySize || h.h > MaxPolySize || h.w*h.h > MaxPolyPixels {
+		return h, fmt.Errorf("invalid .poly size: %dx%d", h.w, h.h)
+	}
+
+	var widths [4]byte
+	if _, err := io.ReadFull(r, widths[:]); err != nil {
+		return h, fmt.Errorf("error reading .poly header: %s", err)
+	}
+	for _, b := range widths {
+		if b > 16 {
+			return h, fmt.Errorf("invalid .poly bit width: %d", b)
+		}
+	}
+	h.countBits, h.xBits, h.yBits, h.colorBits = int(widths[0]), int(widths[1]), int(widths[2]), int(widths[3])
+
+	if h.colorBits < 1 || h.colorBits > 8 {
+		return h, fmt.Errorf("invalid .poly color bits: %d", h.colorBits)
+	}
+
+	// coordinates need at least 1 bit, unless there is only one position (see expandCoord)
+	if h.xBits == 0 && h.w > 1 || h.yBits == 0 && h.h > 1 {
+		return h, fmt.Errorf("invalid .poly coordinate bits for a %dx%d image: %d, %d", h.w, h.h, h.xBits, h.yBits)
+	}
+
+	return h, nil
+}
+
+func decodePolyImage(r io.Reader) (image.Image, error) {
+	cd, err := DecodePoly(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return cd.Image(), nil
+}
+
+func decodePolyConfig(r io.Reader) (image.Config, error) {
+	h, err := readPolyHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{ColorModel: color.RGBAModel, Width: h.w, Height: h.h}, nil
+}
+
+// bitWriter packs values into a byte slice, most significant bit first.
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc int
+}
+
+func (w *bitWriter) write(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.acc = w.acc<<1 | (v>>uint(i))&1
+		w.nacc++
+
+		if w.nacc == 8 {
+			w.buf = append(w.buf, byte(w.acc))
+			w.acc, w.nacc = 0, 0
+		}
+	}
+}
+
+// bytes returns the packed values, padding the last byte with zeros.
+func (w *bitWriter) bytes() []byte {
+	if w.nacc == 0 {
+		return w.buf
+	}
+
+	return append(w.buf[:len(w.buf):len(w.buf)], byte(w.acc<<uint(8-w.nacc)))
+}
+
+// bitReader reads values packed by bitWriter. Reading past the end of data returns zeros and sets overrun.
+type bitReader struct {
+	data    []byte
+	pos     int // in bits
+	overrun bool
+}
+
+func (r *bitReader) read(n int) uint64 {
+	var result uint64
+
+	for i := 0; i < n; i++ {
+		bit := uint64(0)
+		if r.pos/8 < len(r.data) {
+			bit = uint64(r.data[r.pos/8]>>uint(7-r.pos%8)) & 1
+		} else {
+			r.overrun = true
+		}
+
+		result = result<<1 | bit
+		r.pos++
+	}
+
+	return result
+}
@@ -0,0 +1,150 @@
+package polygen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestPolyRoundTrip(t *testing.T) {
+	for _, transparent := range []bool{false, true} {
+		c := randomCandidate(70, 50, 20)
+		c.Transparent = transparent
+		c.Polygons[0].Points = append(c.Polygons[0].Points, Point{69, 49}, Point{0, 0}, Point{35, 0}, Point{12, 49})
+
+		var buf bytes.Buffer
+		if err := EncodePoly(&buf, c); err != nil {
+			t.Fatal(err)
+		}
+		encoded := buf.Bytes()
+
+		actual, err := DecodePoly(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, c) {
+			t.Fatalf("transparent=%v: expected %+v, got: %+v", transparent, c, actual)
+		}
+
+		// the registered image decoder renders the polygons
+		img, format, err := image.Decode(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format != "poly" {
+			t.Errorf("expected format poly, got: %s", format)
+		}
+		if !bytes.Equal(img.(*image.RGBA).Pix, c.Image().Pix) {
+			t.Errorf("transparent=%v: decoded image differs from the render of the candidate", transparent)
+		}
+
+		config, _, err := image.DecodeConfig(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Width != 70 || config.Height != 50 {
+			t.Errorf("expected 70x50, got: %dx%d", config.Width, config.Height)
+		}
+
+		if _, err := DecodePoly(bytes.NewReader(encoded[:len(encoded)-10])); err == nil {
+			t.Errorf("expected error for truncated data")
+		}
+	}
+}
+
+func TestPolyHugeImage(t *testing.T) {
+	// a tiny header that claims to be MaxPolySize x MaxPolySize
+	var buf bytes.Buffer
+	buf.WriteString(PolyMagic)
+	buf.Write([]byte{PolyVersion, 0, 0x80, 0x80, 0x02, 0x80, 0x80, 0x02, 0, 0, 1, 1, 8})
+
+	if _, err := DecodePoly(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Errorf("expected error for a %dx%d image", MaxPolySize, MaxPolySize)
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Errorf("expected error for the config of a %dx%d image", MaxPolySize, MaxPolySize)
+	}
+}
+
+func TestPolyMalformedHeader(t *testing.T) {
+	headers := map[string][]byte{
+		// a 10x10 image with 0 bits for x, which can't hold the 10 positions
+		"zero coordinate bits": {PolyVersion, 0, 10, 10, 1, 0, 0, 4, 8},
+		"unknown flags":        {PolyVersion, 0x40, 10, 10, 1, 0, 4, 4, 8},
+		"two renderers":        {PolyVersion, polyScanline | polyVector, 10, 10, 1, 0, 4, 4, 8},
+	}
+
+	for name, header := range headers {
+		var buf bytes.Buffer
+		buf.WriteString(PolyMagic)
+		buf.Write(header)
+		buf.Write(make([]byte, 16))
+
+		if _, err := DecodePoly(bytes.NewReader(buf.Bytes())); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if _, _, err := image.Decode(bytes.NewReader(buf.Bytes())); err == nil {
+			t.Errorf("%s: expected error from image.Decode", name)
+		}
+	}
+
+	// a single row or column needs no bits at all
+	c := &Candidate{W: 1, H: 10, Polygons: []*Polygon{{Points: []Point{{0, 0}, {0, 9}, {0, 5}}, Color: color.RGBA{255, 0, 0, 255}}}}
+	var buf bytes.Buffer
+	if err := EncodePoly(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodePoly(&buf); err != nil {
+		t.Errorf("unexpected err for a 1x10 image: %s", err)
+	}
+}
+
+func TestPolyRenderOptions(t *testing.T) {
+	for _, r := range allRenderers() {
+		c := randomCandidate(30, 30, 5)
+		c.SetRenderer(r)
+
+		var buf bytes.Buffer
+		if err := EncodePoly(&buf, c); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := DecodePoly(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual.Renderer().Name() != r.Name() || effectiveRenderOptions(actual.Renderer()) != effectiveRenderOptions(r) {
+			t.Errorf("expected %s, got: %s", describeRenderer(r), describeRenderer(actual.Renderer()))
+		}
+		if !bytes.Equal(actual.Image().Pix, c.Image().Pix) {
+			t.Errorf("%s: decoded image differs from the original", describeRenderer(r))
+		}
+	}
+}
+
+func TestBitPacking(t *testing.T) {
+	var w bitWriter
+	values := []struct {
+		v uint64
+		n int
+	}{{5, 3}, {0, 0}, {1023, 10}, {0, 1}, {77, 7}, {1, 1}}
+
+	for _, v := range values {
+		w.write(v.v, v.n)
+	}
+
+	r := bitReader{data: w.bytes()}
+	for _, v := range values {
+		if actual := r.read(v.n); actual != v.v {
+			t.Errorf("expected %d in %d bits, got: %d", v.v, v.n, actual)
+		}
+	}
+	if r.overrun {
+		t.Errorf("unexpected overrun")
+	}
+	if len(w.bytes()) != 3 {
+		t.Errorf("expected 22 bits to pack into 3 bytes, got: %d", len(w.bytes()))
+	}
+}
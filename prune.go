@@ -0,0 +1,117 @@
+package polygen
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Prune compacts c by greedily dropping polygons, and then redundant vertices, for as long as its fitness
+// against refImg stays within tolerance of the original (e.g. a tolerance of 0.01 allows the fitness to get
+// 1% worse). Polygons are tried in order of how little they contribute, as measured by removing each one in
+// turn. Fitness is evaluated the same way as an Evolver configured with options would. The returned
+// Candidate has been rendered and evaluated; c is not modified.
+func Prune(c *Candidate, refImg image.Image, tolerance float64, options Options) (*Candidate, error) {
+	e, err := NewEvolver(refImg, "", "", options)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.W != e.refImgRGBA.Bounds().Dx() || c.H != e.refImgRGBA.Bounds().Dy() {
+		return nil, fmt.Errorf("candidate size %dx%d does not match reference image %v", c.W, c.H, e.refImgRGBA.Bounds())
+	}
+
+	best := c.copyOf()
+	e.renderAndEvaluate(best)
+	limit := uint64(float64(best.Fitness) * (1 + tolerance))
+
+	log.Printf("pruning %d polygons, %d vertices, fitness: %d, limit: %d", len(best.Polygons), best.vertexCount(), best.Fitness, limit)
+
+	// measure the contribution of each polygon by removing it
+	keep := make([]bool, len(c.Polygons))
+	for i := range keep {
+		keep[i] = true
+	}
+
+	without := make([]uint64, len(c.Polygons))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	for i := range c.Polygons {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cand := c.subset(keep, i)
+			e.renderAndEvaluate(cand)
+			without[i] = cand.Fitness
+		}(i)
+	}
+	wg.Wait()
+
+	// then drop them, starting from the least useful
+	order := make([]int, len(c.Polygons))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return without[order[a]] < without[order[b]] })
+
+	for _, i := range order {
+		if len(best.Polygons) == 1 {
+			break
+		}
+
+		cand := c.subset(keep, i)
+		e.renderAndEvaluate(cand)
+		if cand.Fitness <= limit {
+			best = cand
+			keep[i] = false
+		}
+	}
+
+	// then try dropping individual vertices
+	for pi := range best.Polygons {
+		for vi := len(best.Polygons[pi].Points) - 1; vi >= 0 && len(best.Polygons[pi].Points) > MinPolygonPoints; vi-- {
+			cand := best.copyOf()
+			points := cand.Polygons[pi].Points
+			cand.Polygons[pi].Points = append(points[:vi], points[vi+1:]...)
+
+			e.renderAndEvaluate(cand)
+			if cand.Fitness <= limit {
+				best = cand
+			}
+		}
+	}
+
+	log.Printf("pruned to %d polygons, %d vertices, fitness: %d", len(best.Polygons), best.vertexCount(), best.Fitness)
+
+	return best, nil
+}
+
+// subset returns a copy of the Candidate with only the polygons marked in keep, additionally leaving out
+// the polygon at index drop.
+func (c *Candidate) subset(keep []bool, drop int) *Candidate {
+	result := &Candidate{W: c.W, H: c.H, Transparent: c.Transparent, Linear: c.Linear, renderer: c.renderer}
+	for i, poly := range c.Polygons {
+		if keep[i] && i != drop {
+			result.Polygons = append(result.Polygons, poly.copyOf())
+		}
+	}
+
+	return result
+}
+
+// vertexCount returns the total number of points in all of the Candidate's polygons.
+func (c *Candidate) vertexCount() int {
+	result := 0
+	for _, poly := range c.Polygons {
+		result += len(poly.Points)
+	}
+
+	return result
+}
@@ -0,0 +1,49 @@
+package polygen
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	opaque := color.RGBA{255, 0, 0, 255}
+
+	c := &Candidate{W: 20, H: 20}
+	c.Polygons = []*Polygon{
+		square(2, 2, 8, 8, color.RGBA{0, 255, 0, 255}), // hidden under the next one
+		square(0, 0, 10, 10, opaque),
+		{Points: []Point{{10, 10}, {15, 10}, {19, 10}, {19, 19}, {10, 19}}, Color: opaque}, // redundant vertex at 15,10
+	}
+
+	// the reference is exactly what c renders
+	c.renderImage()
+	ref := image.NewRGBA(c.img.Bounds())
+	copy(ref.Pix, c.img.Pix)
+
+	pruned, err := Prune(c, ref, 0, Options{})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	if pruned.Fitness != 0 {
+		t.Fatalf("expected fitness to stay at 0, got: %d", pruned.Fitness)
+	}
+
+	if len(pruned.Polygons) != 2 {
+		t.Fatalf("expected the hidden polygon to be pruned, got %d polygons", len(pruned.Polygons))
+	}
+
+	if len(pruned.Polygons[1].Points) != 4 {
+		t.Fatalf("expected the redundant vertex to be pruned, got: %+v", pruned.Polygons[1].Points)
+	}
+
+	if len(c.Polygons) != 3 {
+		t.Fatalf("original candidate was modified")
+	}
+
+	_, err = Prune(c, image.NewRGBA(image.Rect(0, 0, 10, 10)), 0, Options{})
+	if err == nil {
+		t.Fatalf("expected error for mismatched reference size")
+	}
+}
@@ -0,0 +1,183 @@
+package polygen
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxPSNR is the PSNR reported for identical images, whose PSNR would otherwise be infinite.
+	MaxPSNR = 100
+
+	// StatusInterval is the minimum time between updates of Options.Status, which the Server polls once a second.
+	StatusInterval = time.Second
+)
+
+// Quality describes how closely an image matches the reference image, in terms that (unlike Fitness) are
+// comparable across image sizes and metrics.
+type Quality struct {
+	Similarity float64 // 100 minus the mean absolute error, as a percentage of the full range of a channel
+	PSNR       float64 // peak signal-to-noise ratio in dB, up to MaxPSNR
+	SSIM       float64 // structural similarity of the luminance, 1 for identical images
+}
+
+// String formats q. SSIM is left out if it is zero, since report does not always measure it.
+func (q Quality) String() string {
+	if q.SSIM == 0 {
+		return fmt.Sprintf("similarity: %.2f%%, PSNR: %.2f dB", q.Similarity, q.PSNR)
+	}
+
+	return fmt.Sprintf("similarity: %.2f%%, PSNR: %.2f dB, SSIM: %.4f", q.Similarity, q.PSNR, q.SSIM)
+}
+
+// Meets reports whether q is at least as good as target in each of the measures that are non-zero in target.
+func (q Quality) Meets(target Quality) bool {
+	return q.Similarity >= target.Similarity && q.PSNR >= target.PSNR && q.SSIM >= target.SSIM
+}
+
+// MeasureQuality compares img with ref, which must have the same bounds.
+func MeasureQuality(ref, img *image.RGBA) (Quality, error) {
+	result, err := measureError(ref, img)
+	if err != nil {
+		return Quality{}, err
+	}
+
+	result.SSIM = meanSSIM(luminance(ref), luminance(img), ref.Bounds().Dx(), ref.Bounds().Dy(), nil)
+
+	return result, nil
+}
+
+// measureError is MeasureQuality without the SSIM, which is far more expensive than the other measures.
+func measureError(ref, img *image.RGBA) (Quality, error) {
+	if err := checkFitnessArgs(ref, img, nil); err != nil {
+		return Quality{}, err
+	}
+
+	var absError, sqError uint64
+	for i := range ref.Pix {
+		d := uint64(diffUint8(ref.Pix[i], img.Pix[i]))
+		absError += d
+		sqError += d * d
+	}
+
+	n := float64(len(ref.Pix))
+	result := Quality{
+		Similarity: 100 * (1 - float64(absError)/(255*n)),
+		PSNR:       MaxPSNR,
+	}
+
+	if sqError > 0 {
+		result.PSNR = math.Min(MaxPSNR, 10*math.Log10(255*255/(float64(sqError)/n)))
+	}
+
+	return result, nil
+}
+
+// ParseQuality parses a comma-separated list of measure:value pairs into a Quality, e.g. "psnr:30,ssim:0.9".
+// The measures are "similarity" (a percentage), "psnr" and "ssim". Returns the zero Quality for an empty string.
+func ParseQuality(s string) (Quality, error) {
+	var result Quality
+	if s == "" {
+		return result, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.Split(part, ":")
+		if len(kv) != 2 {
+			return Quality{}, fmt.Errorf("invalid quality %q, expected measure:value", part)
+		}
+
+		v, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil || v < 0 {
+			return Quality{}, fmt.Errorf("invalid value in %q", part)
+		}
+
+		switch strings.ToLower(kv[0]) {
+		case "similarity":
+			result.Similarity = v
+		case "psnr":
+			result.PSNR = v
+		case "ssim":
+			result.SSIM = v
+		default:
+			return Quality{}, fmt.Errorf("unknown quality measure %q, expected similarity, psnr or ssim", kv[0])
+		}
+	}
+
+	return result, nil
+}
+
+// Status is a snapshot of the progress of an Evolver.
+type Status struct {
+	Generation int
+	Fitness    uint64
+	Quality    Quality
+}
+
+// SafeStatus is a Status protected by a RWMutex, so that the Evolver can update it while the Server displays it.
+type SafeStatus struct {
+	status Status
+	mux    sync.RWMutex
+}
+
+func NewSafeStatus() *SafeStatus {
+	return &SafeStatus{}
+}
+
+func (s *SafeStatus) Update(status Status) {
+	s.mux.Lock()
+	s.status = status
+	s.mux.Unlock()
+}
+
+func (s *SafeStatus) Value() Status {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.status
+}
+
+// report prints and publishes (see Options.Status) the progress of the Evolver, and reports whether best has
+// reached Options.StopAt. The SSIM is only measured when it is published or needed for StopAt; the status is
+// published at most once per StatusInterval, and when the run stops.
+func (e *Evolver) report(stats *Stats, best, worst *Candidate) bool {
+	checkStop := e.options.StopAt != (Quality{}) && e.refImgRGBA == e.fullRefImgRGBA
+	publish := e.options.Status != nil && time.Since(e.statusTime) >= StatusInterval
+	if e.options.Quiet && !publish && !checkStop {
+		return false
+	}
+
+	quality, err := measureError(e.refImgRGBA, best.img)
+	if err != nil {
+		log.Fatalf("error measuring quality: %s", err)
+	}
+
+	stop := checkStop && quality.Meets(Quality{Similarity: e.options.StopAt.Similarity, PSNR: e.options.StopAt.PSNR})
+	if stop || publish {
+		quality.SSIM = meanSSIM(luminance(e.refImgRGBA), luminance(best.img), best.img.Bounds().Dx(), best.img.Bounds().Dy(), nil)
+		stop = stop && quality.Meets(e.options.StopAt)
+	}
+
+	if !e.options.Quiet {
+		stats.Print(best, worst, quality, e.generation, e.generationsSinceChange)
+	}
+
+	if e.options.Status != nil && (publish || stop) {
+		e.options.Status.Update(Status{Generation: e.generation, Fitness: best.Fitness, Quality: quality})
+		e.statusTime = time.Now()
+	}
+
+	if stop {
+		if !e.options.Quiet {
+			log.Printf("reached the target quality at generation %d: %s", e.generation, quality)
+		}
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package polygen
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestMeasureQuality(t *testing.T) {
+	img1 := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	img2 := image.NewRGBA(img1.Bounds())
+	for i := range img1.Pix {
+		img1.Pix[i] = uint8(rand.Intn(255))
+		img2.Pix[i] = img1.Pix[i] + 1
+	}
+
+	q, err := MeasureQuality(img1, img1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Similarity != 100 || q.PSNR != MaxPSNR || math.Abs(q.SSIM-1) > 1e-9 {
+		t.Errorf("expected a perfect score for identical images, got: %s", q)
+	}
+
+	// an error of 1 in every channel
+	q, _ = MeasureQuality(img1, img2)
+	if expected := 100 * (1 - 1.0/255); math.Abs(q.Similarity-expected) > 1e-9 {
+		t.Errorf("expected similarity %f, got: %f", expected, q.Similarity)
+	}
+	if expected := 10 * math.Log10(255*255); math.Abs(q.PSNR-expected) > 1e-9 {
+		t.Errorf("expected PSNR %f, got: %f", expected, q.PSNR)
+	}
+
+	if _, err := MeasureQuality(img1, image.NewRGBA(image.Rect(0, 0, 10, 10))); err == nil {
+		t.Errorf("expected error for images of different sizes")
+	}
+}
+
+func TestParseQuality(t *testing.T) {
+	q, err := ParseQuality("similarity:95,psnr:30,ssim:0.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Quality{Similarity: 95, PSNR: 30, SSIM: 0.9}); q != expected {
+		t.Errorf("expected %+v, got: %+v", expected, q)
+	}
+
+	if q, _ := ParseQuality(""); q != (Quality{}) {
+		t.Errorf("expected zero Quality, got: %+v", q)
+	}
+
+	for _, s := range []string{"psnr", "psnr:x", "foo:1", "ssim:-1"} {
+		if _, err := ParseQuality(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+
+	if !(Quality{95, 30, 0.9}).Meets(Quality{PSNR: 30}) || (Quality{95, 29, 0.9}).Meets(Quality{PSNR: 30}) {
+		t.Errorf("Meets should only consider the non-zero measures of the target")
+	}
+}
+
+func TestStopAt(t *testing.T) {
+	ref := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	status := NewSafeStatus()
+
+	// any candidate is at least 1% similar to a black image
+	e, err := NewEvolver(ref, "", "", Options{Quiet: true, StopAt: Quality{Similarity: 1}, Status: status})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Run(1000, 5, nil)
+
+	if e.generation != 1 {
+		t.Errorf("expected to stop after the first generation, stopped after: %d", e.generation)
+	}
+	if s := status.Value(); s.Generation != 1 || s.Quality.Similarity < 1 {
+		t.Errorf("expected final status to be published, got: %+v", s)
+	}
+}
@@ -0,0 +1,278 @@
+package polygen
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	// polygons smaller than this many square pixels are considered degenerate
+	RecycleMinArea = 1.0
+
+	// polygons with a (non-premultiplied) alpha below this are considered invisible
+	RecycleMinAlpha = 2
+
+	// polygons that visibly contribute to fewer than this many pixels are considered hidden
+	RecycleMinVisiblePixels = 4
+
+	// a polygon visibly contributes to a pixel if its alpha, attenuated by the polygons above it, is at least this
+	minVisibleContribution = 0.02
+
+	// the reference image is divided into cells of this size when looking for high-error regions
+	errorCellSize = 8
+
+	// number of attempts at finding a useful replacement for each recycled polygon
+	recycleAttempts = 3
+)
+
+// recycle finds polygons in the most fit candidate that contribute nothing to the image (because they are
+// zero-area, transparent, or hidden under other polygons), and respawns them on top of the others in regions
+// of high error, colored from the reference image. A respawned polygon is only kept if it does not make the
+// fitness any worse. Returns the number of polygons recycled.
+func (e *Evolver) recycle() int {
+	best := e.mostFit
+	degenerate := degeneratePolygons(best)
+	if len(degenerate) == 0 {
+		return 0
+	}
+
+	cells := e.errorCells(best)
+	recycled := 0
+
+	// process from the top down, so that moving a polygon to the top does not disturb the remaining indexes
+	sort.Sort(sort.Reverse(sort.IntSlice(degenerate)))
+
+	for _, i := range degenerate {
+		for attempt := 0; attempt < recycleAttempts; attempt++ {
+			cand := best.copyOf()
+			poly := e.spawnPolygon(cand.W, cand.H, cells)
+
+			cand.Polygons = append(append(cand.Polygons[:i], cand.Polygons[i+1:]...), poly)
+			e.renderAndEvaluate(cand)
+
+			if cand.Fitness <= best.Fitness {
+				best = cand
+				recycled++
+				break
+			}
+		}
+	}
+
+	if recycled > 0 {
+		e.mostFit = best
+		e.candidates[0] = best
+	}
+
+	return recycled
+}
+
+// degeneratePolygons returns the indexes of the polygons in c that are too small, too transparent,
+// or too hidden to contribute to the rendered image.
+func degeneratePolygons(c *Candidate) []int {
+	var result []int
+
+	visible := visiblePixels(c)
+	for i, poly := range c.Polygons {
+		_, _, _, a := poly.RGBA()
+
+		if poly.area() < RecycleMinArea || a>>8 < RecycleMinAlpha || visible[i] < RecycleMinVisiblePixels {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+// area returns the area of the polygon in square pixels, as computed by the shoelace formula.
+func (p *Polygon) area() float64 {
+	sum := 0
+	for i := range p.Points {
+		j := (i + 1) % len(p.Points)
+		sum += p.Points[i].X*p.Points[j].Y - p.Points[j].X*p.Points[i].Y
+	}
+
+	return math.Abs(float64(sum)) / 2
+}
+
+// visiblePixels returns, for each polygon in c, the number of pixels to which it visibly contributes, taking
+// into account the polygons drawn over it.
+func visiblePixels(c *Candidate) []int {
+	result := make([]int, len(c.Polygons))
+
+	// the fraction of each pixel's color that is still determined by polygons further down
+	transmittance := make([]float64, c.W*c.H)
+	for i := range transmittance {
+		transmittance[i] = 1
+	}
+
+	for k := len(c.Polygons) - 1; k >= 0; k-- {
+		poly := c.Polygons[k]
+		_, _, _, a := poly.RGBA()
+		alpha := float64(a) / 0xffff
+
+		scanPolygon(poly.Points, c.W, c.H, func(x0, x1, y int) {
+			for i := y*c.W + x0; i < y*c.W+x1; i++ {
+				if alpha*transmittance[i] >= minVisibleContribution {
+					result[k]++
+				}
+				transmittance[i] *= 1 - alpha
+			}
+		})
+	}
+
+	return result
+}
+
+// scanPolygon calls span for each horizontal run [x0, x1) of pixels on row y whose centers lie inside the
+// polygon (using the even-odd rule), clipped to a w x h image.
+func scanPolygon(points []Point, w, h int, span func(x0, x1, y int)) {
+	scanFill(points, w, h, false, span)
+}
+
+// scanFill is like scanPolygon, but uses the non-zero winding rule if nonZero is set.
+func scanFill(points []Point, w, h int, nonZero bool, span func(x0, x1, y int)) {
+	minY, maxY := h, -1
+	for _, p := range points {
+		if p.Y < minY {
+			minY = p.Y
+		}
+		if p.Y > maxY {
+			maxY = p.Y
+		}
+	}
+
+	if minY < 0 {
+		minY = 0
+	}
+	if maxY > h-1 {
+		maxY = h - 1
+	}
+
+	type crossing struct {
+		x   float64
+		dir int // +1 for edges going down, -1 for edges going up
+	}
+
+	var xs []crossing
+	for y := minY; y <= maxY; y++ {
+		cy := float64(y) + 0.5
+		xs = xs[:0]
+
+		for i := range points {
+			a, b := points[i], points[(i+1)%len(points)]
+			ay, by := float64(a.Y), float64(b.Y)
+
+			if (ay <= cy) != (by <= cy) {
+				dir := 1
+				if by < ay {
+					dir = -1
+				}
+				xs = append(xs, crossing{float64(a.X) + (cy-ay)*float64(b.X-a.X)/(by-ay), dir})
+			}
+		}
+
+		sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+
+		winding := 0
+		for i := 0; i+1 < len(xs); i++ {
+			if nonZero {
+				winding += xs[i].dir
+			} else {
+				winding ^= 1
+			}
+
+			if winding == 0 {
+				continue
+			}
+
+			// pixel x is inside if its center x + 0.5 is in [xs[i], xs[i+1])
+			x0 := int(math.Ceil(xs[i].x - 0.5))
+			x1 := int(math.Ceil(xs[i+1].x - 0.5))
+
+			if x0 < 0 {
+				x0 = 0
+			}
+			if x1 > w {
+				x1 = w
+			}
+			if x0 < x1 {
+				span(x0, x1, y)
+			}
+		}
+	}
+}
+
+// errorCells divides the image into cells, and returns the cumulative sum of the (weighted) error between c
+// and the reference image over the cells, for weighted random selection.
+func (e *Evolver) errorCells(c *Candidate) []uint64 {
+	cols := (c.W + errorCellSize - 1) / errorCellSize
+	rows := (c.H + errorCellSize - 1) / errorCellSize
+	result := make([]uint64, cols*rows)
+
+	ref := e.refImgRGBA.Pix
+	for y := 0; y < c.H; y++ {
+		for x := 0; x < c.W; x++ {
+			i := (y*c.W + x) * 4
+			d := uint64(diffUint8(ref[i], c.img.Pix[i])) + uint64(diffUint8(ref[i+1], c.img.Pix[i+1])) + uint64(diffUint8(ref[i+2], c.img.Pix[i+2]))
+			if e.weights != nil {
+				d *= uint64(e.weights[y*c.W+x])
+			}
+			result[(y/errorCellSize)*cols+x/errorCellSize] += d
+		}
+	}
+
+	for i := 1; i < len(result); i++ {
+		result[i] += result[i-1]
+	}
+
+	return result
+}
+
+// spawnPolygon creates a small random polygon centered in a cell chosen with probability proportional to its
+// error, colored like the reference image at its center.
+func (e *Evolver) spawnPolygon(w, h int, cells []uint64) *Polygon {
+	cols := (w + errorCellSize - 1) / errorCellSize
+
+	cell := 0
+	if total := cells[len(cells)-1]; total > 0 {
+		r := uint64(rand.Int63n(int64(total)))
+		cell = sort.Search(len(cells), func(i int) bool { return cells[i] > r })
+	} else {
+		cell = rand.Intn(len(cells))
+	}
+
+	cx := clampInt((cell%cols)*errorCellSize+rand.Intn(errorCellSize), 0, w-1)
+	cy := clampInt((cell/cols)*errorCellSize+rand.Intn(errorCellSize), 0, h-1)
+
+	radius := w
+	if h < radius {
+		radius = h
+	}
+	radius = radius/10 + 2
+
+	result := &Polygon{}
+	for i := 0; i < MinPolygonPoints; i++ {
+		x := clampInt(cx+rand.Intn(2*radius+1)-radius, 0, w-1)
+		y := clampInt(cy+rand.Intn(2*radius+1)-radius, 0, h-1)
+		result.addPoint(Point{x, y})
+	}
+
+	nrgba := color.NRGBAModel.Convert(e.refImgRGBA.RGBAAt(cx, cy)).(color.NRGBA)
+	nrgba.A = uint8(128 + rand.Intn(128))
+	result.Color = color.RGBAModel.Convert(nrgba)
+
+	return result
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+
+	return v
+}
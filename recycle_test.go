@@ -0,0 +1,105 @@
+package polygen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+)
+
+func square(x0, y0, x1, y1 int, c color.Color) *Polygon {
+	return &Polygon{Points: []Point{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}, Color: c}
+}
+
+func TestPolygonArea(t *testing.T) {
+	if a := square(0, 0, 10, 5, color.Black).area(); a != 50 {
+		t.Errorf("expected area 50, got: %g", a)
+	}
+
+	line := &Polygon{Points: []Point{{0, 0}, {5, 5}, {10, 10}}}
+	if a := line.area(); a != 0 {
+		t.Errorf("expected area 0, got: %g", a)
+	}
+}
+
+func TestScanPolygon(t *testing.T) {
+	count := 0
+	scanPolygon(square(2, 2, 12, 7, nil).Points, 100, 100, func(x0, x1, y int) {
+		count += x1 - x0
+	})
+
+	if count != 50 {
+		t.Errorf("expected 50 pixels, got: %d", count)
+	}
+
+	// clipped to the image
+	count = 0
+	scanPolygon(square(-5, -5, 5, 5, nil).Points, 100, 100, func(x0, x1, y int) {
+		if x0 < 0 || x1 > 100 || y < 0 || y >= 100 {
+			t.Fatalf("span out of bounds: %d-%d, %d", x0, x1, y)
+		}
+		count += x1 - x0
+	})
+
+	if count != 25 {
+		t.Errorf("expected 25 pixels, got: %d", count)
+	}
+}
+
+func TestDegeneratePolygons(t *testing.T) {
+	opaque := color.RGBA{255, 0, 0, 255}
+
+	c := &Candidate{W: 20, H: 20}
+	c.Polygons = []*Polygon{
+		square(2, 2, 8, 8, opaque),                                 // 0: hidden under 4
+		square(0, 0, 5, 5, color.RGBA{}),                           // 1: transparent
+		{Points: []Point{{0, 0}, {5, 5}, {10, 10}}, Color: opaque}, // 2: zero area
+		square(10, 10, 20, 20, opaque),                             // 3: fine
+		square(0, 0, 10, 10, opaque),                               // 4: covers 0
+	}
+
+	expected := []int{0, 1, 2}
+	if actual := degeneratePolygons(c); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v, got: %v", expected, actual)
+	}
+}
+
+func TestRecycle(t *testing.T) {
+	// a solid reference, so that a polygon respawned in its color can only help
+	ref := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	draw.Draw(ref, ref.Bounds(), &image.Uniform{color.RGBA{200, 0, 0, 255}}, image.ZP, draw.Src)
+
+	e, err := NewEvolver(ref, "", "", Options{Quiet: true})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	invisible := &Polygon{Points: []Point{{5, 5}, {40, 10}, {20, 40}}, Color: color.RGBA{}}
+	e.mostFit = randomCandidate(50, 50, 10)
+	e.mostFit.Polygons[0] = invisible
+	e.candidates[0] = e.mostFit
+	e.renderAndEvaluate(e.mostFit)
+	before := e.mostFit.Fitness
+
+	if recycled := e.recycle(); recycled < 1 {
+		t.Fatalf("expected at least 1 polygon to be recycled, got: %d", recycled)
+	}
+
+	if e.mostFit.Fitness > before {
+		t.Fatalf("recycle made fitness worse: %d -> %d", before, e.mostFit.Fitness)
+	}
+
+	if len(e.mostFit.Polygons) != 10 {
+		t.Fatalf("expected polygon count to be unchanged, got: %d", len(e.mostFit.Polygons))
+	}
+
+	for i, poly := range e.mostFit.Polygons {
+		if poly == invisible {
+			t.Fatalf("expected the transparent polygon to be replaced, found it at %d", i)
+		}
+	}
+	if _, _, _, a := e.mostFit.Polygons[9].RGBA(); a == 0 {
+		t.Errorf("expected a visible polygon to be respawned on top")
+	}
+}
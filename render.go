@@ -0,0 +1,346 @@
+package polygen
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/golang/freetype/raster"
+	"github.com/llgcode/draw2d"
+	"github.com/llgcode/draw2d/draw2dimg"
+	"golang.org/x/image/vector"
+)
+
+// Renderer rasterizes the polygons of a Candidate. See ParseRenderer.
+type Renderer interface {
+	// Name identifies the renderer, e.g. on the command line.
+	Name() string
+
+	// NewCanvas returns a Canvas that fills polygons onto dst, by passing the spans they cover to painter
+	// (which composites them onto dst).
+	NewCanvas(dst draw.Image, painter draw2dimg.Painter) Canvas
+}
+
+// Canvas fills polygons for a Renderer.
+type Canvas interface {
+	// Fill fills the polygon with the given points (translated by -origin) with c.
+	Fill(points []Point, origin image.Point, c color.Color)
+}
+
+// FillRule decides which parts of a self-intersecting polygon are filled.
+type FillRule int
+
+const (
+	FillDefault FillRule = iota // the renderer's own rule: non-zero for Vector, even-odd otherwise
+	FillEvenOdd                 // fill areas enclosed an odd number of times
+	FillNonZero                 // fill areas with a non-zero winding number
+)
+
+// ParseFillRule returns the FillRule with the given name: "evenodd" or "nonzero". An empty name means FillDefault.
+func ParseFillRule(name string) (FillRule, error) {
+	switch name {
+	case "":
+		return FillDefault, nil
+	case "evenodd":
+		return FillEvenOdd, nil
+	case "nonzero":
+		return FillNonZero, nil
+	default:
+		return FillDefault, fmt.Errorf("unknown fill rule: %q", name)
+	}
+}
+
+// fillRuleName is the inverse of ParseFillRule.
+func fillRuleName(f FillRule) string {
+	switch f {
+	case FillEvenOdd:
+		return "evenodd"
+	case FillNonZero:
+		return "nonzero"
+	default:
+		return ""
+	}
+}
+
+// RenderOptions configures a Renderer.
+type RenderOptions struct {
+	Aliased  bool     // if true, don't anti-alias edges: each pixel is either covered by a polygon or not
+	FillRule FillRule // how self-intersecting polygons are filled
+}
+
+// ParseRenderer returns the Renderer with the given name, configured with options: "draw2d" (the default, also
+// used for an empty name), "scanline" (which is always aliased) or "vector" (which only supports FillNonZero).
+func ParseRenderer(name string, options RenderOptions) (Renderer, error) {
+	switch name {
+	case "", "draw2d":
+		return Draw2D{options}, nil
+	case "scanline":
+		return Scanline{options}, nil
+	case "vector":
+		if options.FillRule == FillEvenOdd {
+			return nil, fmt.Errorf("the vector renderer does not support the even-odd fill rule")
+		}
+		return Vector{options}, nil
+	default:
+		return nil, fmt.Errorf("unknown renderer: %q", name)
+	}
+}
+
+// RendererFlags defines the -renderer, -aa and -fill flags on fs, for the commands that evolve images. Once fs has
+// been parsed, the returned function returns the Renderer that they select, see ParseRenderer.
+func RendererFlags(fs *flag.FlagSet) func() (Renderer, error) {
+	name := fs.String("renderer", "draw2d", "polygon renderer: draw2d, scanline (fastest, no anti-aliasing) or vector")
+	antialias := fs.Bool("aa", true, "anti-alias polygon edges (not supported by -renderer scanline, which is always aliased)")
+	fillRule := fs.String("fill", "", "fill rule for self-intersecting polygons: evenodd or nonzero (default: the renderer's own, evenodd except for vector)")
+
+	return func() (Renderer, error) {
+		rule, err := ParseFillRule(*fillRule)
+		if err != nil {
+			return nil, err
+		}
+
+		return ParseRenderer(*name, RenderOptions{Aliased: !*antialias, FillRule: rule})
+	}
+}
+
+// rendererOptions returns the RenderOptions that r was configured with, see ParseRenderer. These are what is
+// saved (and compared) with a checkpoint's renderer, so defaults such as FillDefault are kept as they are.
+func rendererOptions(r Renderer) RenderOptions {
+	switch r := r.(type) {
+	case Draw2D:
+		return r.RenderOptions
+	case Scanline:
+		return r.RenderOptions
+	case Vector:
+		return r.RenderOptions
+	default:
+		return RenderOptions{}
+	}
+}
+
+// effectiveRenderOptions returns how r actually draws: unlike rendererOptions, the fill rule is resolved to the one
+// that r uses, and Scanline is always Aliased. Two renderers with the same name and effective options draw the
+// same pixels.
+func effectiveRenderOptions(r Renderer) RenderOptions {
+	result := rendererOptions(r)
+
+	switch r.(type) {
+	case Scanline:
+		result.Aliased = true
+	case Vector:
+		result.FillRule = FillNonZero
+	}
+
+	if result.FillRule == FillDefault {
+		result.FillRule = FillEvenOdd
+	}
+
+	return result
+}
+
+// describeRenderer describes how r draws, for messages, e.g. "draw2d (anti-aliased, evenodd)".
+func describeRenderer(r Renderer) string {
+	options := effectiveRenderOptions(r)
+
+	edges := "anti-aliased"
+	if options.Aliased {
+		edges = "aliased"
+	}
+
+	return fmt.Sprintf("%s (%s, %s)", r.Name(), edges, fillRuleName(options.FillRule))
+}
+
+// aliasedPainter turns anti-aliased spans into aliased ones: pixels that are at least half covered are painted
+// fully, and the rest not at all.
+type aliasedPainter struct {
+	draw2dimg.Painter
+}
+
+func (p aliasedPainter) Paint(ss []raster.Span, done bool) {
+	result := ss[:0]
+	for _, s := range ss {
+		if s.Alpha >= 0x8000 {
+			s.Alpha = 0xffff
+			result = append(result, s)
+		}
+	}
+
+	p.Painter.Paint(result, done)
+}
+
+// Draw2D renders with github.com/llgcode/draw2d. By default it anti-aliases edges and uses the even-odd fill rule.
+type Draw2D struct {
+	RenderOptions
+}
+
+func (Draw2D) Name() string { return "draw2d" }
+
+func (r Draw2D) NewCanvas(dst draw.Image, painter draw2dimg.Painter) Canvas {
+	if r.Aliased {
+		painter = aliasedPainter{painter}
+	}
+
+	gc := draw2dimg.NewGraphicContextWithPainter(dst, painter)
+	if r.FillRule == FillNonZero {
+		gc.SetFillRule(draw2d.FillRuleWinding)
+	}
+
+	return &draw2dCanvas{gc: gc}
+}
+
+type draw2dCanvas struct {
+	gc *draw2dimg.GraphicContext
+}
+
+func (c *draw2dCanvas) Fill(points []Point, origin image.Point, col color.Color) {
+	c.gc.SetFillColor(col)
+
+	c.gc.MoveTo(float64(points[0].X-origin.X), float64(points[0].Y-origin.Y))
+	for _, p := range points[1:] {
+		c.gc.LineTo(float64(p.X-origin.X), float64(p.Y-origin.Y))
+	}
+
+	c.gc.Close()
+	c.gc.Fill()
+}
+
+// Scanline is a simple, fast polygon filler that fills each pixel whose center lies inside the polygon (by the
+// even-odd rule, unless FillNonZero is set). It never anti-aliases, so Aliased is implied.
+type Scanline struct {
+	RenderOptions
+}
+
+func (Scanline) Name() string { return "scanline" }
+
+func (r Scanline) NewCanvas(dst draw.Image, painter draw2dimg.Painter) Canvas {
+	b := dst.Bounds()
+	return &scanlineCanvas{w: b.Dx(), h: b.Dy(), nonZero: r.FillRule == FillNonZero, painter: painter}
+}
+
+type scanlineCanvas struct {
+	w, h    int
+	nonZero bool
+	painter draw2dimg.Painter
+	points  []Point
+	spans   []raster.Span
+}
+
+func (c *scanlineCanvas) Fill(points []Point, origin image.Point, col color.Color) {
+	c.points = c.points[:0]
+	for _, p := range points {
+		c.points = append(c.points, Point{p.X - origin.X, p.Y - origin.Y})
+	}
+
+	// compositing dominates, so speed up the common case of painting onto an sRGB image with lookup tables
+	if p, ok := c.painter.(*raster.RGBAPainter); ok && p.Op == draw.Over {
+		table := overTable(col)
+		scanFill(c.points, c.w, c.h, c.nonZero, func(x0, x1, y int) {
+			pix := p.Image.Pix[p.Image.PixOffset(x0, y):p.Image.PixOffset(x1, y)]
+			for i := 0; i < len(pix); i += 4 {
+				pix[i] = table[0][pix[i]]
+				pix[i+1] = table[1][pix[i+1]]
+				pix[i+2] = table[2][pix[i+2]]
+				pix[i+3] = table[3][pix[i+3]]
+			}
+		})
+		return
+	}
+
+	c.spans = c.spans[:0]
+	scanFill(c.points, c.w, c.h, c.nonZero, func(x0, x1, y int) {
+		c.spans = append(c.spans, raster.Span{Y: y, X0: x0, X1: x1, Alpha: 0xffff})
+	})
+
+	c.painter.SetColor(col)
+	c.painter.Paint(c.spans, true)
+}
+
+// overTable returns, for each RGBA channel, the result of compositing col over every possible 8-bit value with
+// full coverage, computed exactly as raster.RGBAPainter does.
+func overTable(col color.Color) (table [4][256]uint8) {
+	const m = 1<<16 - 1
+	cr, cg, cb, ca := col.RGBA()
+	a := (m - ca) * 0x101
+
+	for i, c := range []uint32{cr, cg, cb, ca} {
+		for d := uint32(0); d < 256; d++ {
+			table[i][d] = uint8((d*a + c*m) / m >> 8)
+		}
+	}
+
+	return table
+}
+
+// Vector renders with golang.org/x/image/vector, which anti-aliases edges (unless Aliased is set) like Draw2D,
+// but always uses the non-zero winding fill rule.
+type Vector struct {
+	RenderOptions
+}
+
+func (Vector) Name() string { return "vector" }
+
+func (r Vector) NewCanvas(dst draw.Image, painter draw2dimg.Painter) Canvas {
+	if r.Aliased {
+		painter = aliasedPainter{painter}
+	}
+
+	return &vectorCanvas{bounds: image.Rect(0, 0, dst.Bounds().Dx(), dst.Bounds().Dy()), painter: painter}
+}
+
+type vectorCanvas struct {
+	bounds  image.Rectangle
+	painter draw2dimg.Painter
+	z       vector.Rasterizer
+	mask    *image.Alpha
+	spans   []raster.Span
+}
+
+func (c *vectorCanvas) Fill(points []Point, origin image.Point, col color.Color) {
+	// rasterize the coverage of the polygon's whole bounding box into a mask, in coordinates relative to the
+	// polygon itself, so that the coverage of each pixel doesn't depend on origin or the size of the canvas (as
+	// renderChange requires), and only clip the spans to the canvas
+	poly := &Polygon{Points: points}
+	pb := poly.bounds()
+	r := pb.Sub(origin).Intersect(c.bounds)
+	if r.Empty() {
+		return
+	}
+
+	c.z.Reset(pb.Dx(), pb.Dy())
+	c.z.DrawOp = draw.Src
+	c.z.MoveTo(float32(points[0].X-pb.Min.X), float32(points[0].Y-pb.Min.Y))
+	for _, p := range points[1:] {
+		c.z.LineTo(float32(p.X-pb.Min.X), float32(p.Y-pb.Min.Y))
+	}
+	c.z.ClosePath()
+
+	if c.mask == nil || c.mask.Rect.Dx() < pb.Dx() || c.mask.Rect.Dy() < pb.Dy() {
+		c.mask = image.NewAlpha(image.Rect(0, 0, pb.Dx(), pb.Dy()))
+	}
+	c.z.Draw(c.mask, image.Rect(0, 0, pb.Dx(), pb.Dy()), image.Opaque, image.Point{})
+
+	// and paint each run of equal coverage within the canvas as a span
+	offset := origin.Sub(pb.Min)
+	c.spans = c.spans[:0]
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := (y+offset.Y)*c.mask.Stride + offset.X
+		row := c.mask.Pix[i+r.Min.X : i+r.Max.X]
+
+		for x0 := 0; x0 < len(row); {
+			x1 := x0 + 1
+			for x1 < len(row) && row[x1] == row[x0] {
+				x1++
+			}
+
+			if row[x0] > 0 {
+				c.spans = append(c.spans, raster.Span{Y: y, X0: x0 + r.Min.X, X1: x1 + r.Min.X, Alpha: uint32(row[x0]) * 0x101})
+			}
+			x0 = x1
+		}
+	}
+
+	c.painter.SetColor(col)
+	c.painter.Paint(c.spans, true)
+}
@@ -0,0 +1,163 @@
+package polygen
+
+import (
+	"bytes"
+	"flag"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// renderWith renders a copy of c with r.
+func renderWith(c *Candidate, r Renderer) *Candidate {
+	result := c.copyOf()
+	result.renderer = r
+	result.renderImage()
+
+	return result
+}
+
+// allRenderers returns every renderer, with every combination of RenderOptions it supports.
+func allRenderers() []Renderer {
+	var result []Renderer
+	for _, name := range []string{"draw2d", "scanline", "vector"} {
+		for _, aliased := range []bool{false, true} {
+			for _, rule := range []FillRule{FillDefault, FillEvenOdd, FillNonZero} {
+				if r, err := ParseRenderer(name, RenderOptions{Aliased: aliased, FillRule: rule}); err == nil {
+					result = append(result, r)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+func TestRenderersMatchDraw2DOnRectangles(t *testing.T) {
+	// edges along pixel boundaries are the same with or without anti-aliasing
+	c := &Candidate{W: 50, H: 40}
+	for i := 0; i < 10; i++ {
+		x0, y0 := rand.Intn(40), rand.Intn(30)
+		x1, y1 := x0+1+rand.Intn(10), y0+1+rand.Intn(10)
+		nrgba := color.NRGBA{uint8(rand.Intn(256)), uint8(rand.Intn(256)), uint8(rand.Intn(256)), uint8(rand.Intn(256))}
+		c.Polygons = append(c.Polygons, &Polygon{Points: []Point{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}, Color: color.RGBAModel.Convert(nrgba)})
+	}
+
+	expected := renderWith(c, Draw2D{})
+
+	for _, r := range []Renderer{Scanline{}, Vector{}} {
+		if actual := renderWith(c, r); !bytes.Equal(actual.img.Pix, expected.img.Pix) {
+			diff, _ := FastCompare(actual.img, expected.img)
+			t.Errorf("%s: render of rectangles differs from draw2d by %d", r.Name(), diff)
+		}
+	}
+}
+
+func TestRenderersPixelDiff(t *testing.T) {
+	for _, tc := range []struct {
+		renderer Renderer
+		maxDiff  float64 // mean difference per channel
+	}{
+		// scanline differs along every edge, since it does not anti-alias
+		{Scanline{}, 4},
+
+		// vector only differs where polygons intersect themselves, due to the fill rule
+		{Vector{}, 1},
+	} {
+		total := 0.0
+		for i := 0; i < 20; i++ {
+			c := randomCandidate(100, 80, 20)
+			expected := renderWith(c, Draw2D{}).img
+			diff, _ := FastCompare(expected, renderWith(c, tc.renderer).img)
+			total += float64(diff) / float64(len(expected.Pix))
+		}
+
+		if mean := total / 20; mean > tc.maxDiff {
+			t.Errorf("%s: expected a mean difference from draw2d of at most %g, got: %g", tc.renderer.Name(), tc.maxDiff, mean)
+		}
+	}
+}
+
+func TestParseRenderer(t *testing.T) {
+	for _, name := range []string{"", "draw2d", "scanline", "vector"} {
+		r, err := ParseRenderer(name, RenderOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "" && r.Name() != name {
+			t.Errorf("expected %s, got: %s", name, r.Name())
+		}
+	}
+
+	if _, err := ParseRenderer("foo", RenderOptions{}); err == nil {
+		t.Errorf("expected error for unknown renderer")
+	}
+
+	if _, err := ParseRenderer("vector", RenderOptions{FillRule: FillEvenOdd}); err == nil {
+		t.Errorf("expected error for vector with the even-odd rule")
+	}
+}
+
+func TestFillRule(t *testing.T) {
+	// the center of a pentagram is enclosed twice, so it is only filled by the non-zero rule
+	star := &Candidate{W: 40, H: 40, Polygons: []*Polygon{
+		{Points: []Point{{20, 2}, {31, 36}, {2, 14}, {38, 14}, {9, 36}}, Color: color.RGBA{255, 255, 255, 255}},
+	}}
+
+	for _, tc := range []struct {
+		renderer Renderer
+		filled   bool
+	}{
+		{Draw2D{}, false},
+		{Draw2D{RenderOptions{FillRule: FillNonZero}}, true},
+		{Scanline{}, false},
+		{Scanline{RenderOptions{FillRule: FillNonZero}}, true},
+		{Vector{}, true},
+	} {
+		img := renderWith(star, tc.renderer).img
+		if filled := img.RGBAAt(20, 20).R == 255; filled != tc.filled {
+			t.Errorf("%s %+v: expected center filled: %v, got: %v", tc.renderer.Name(), tc.renderer, tc.filled, filled)
+		}
+	}
+}
+
+func TestAliased(t *testing.T) {
+	c := randomCandidate(60, 50, 10)
+	for _, p := range c.Polygons {
+		p.Color = color.RGBA{255, 255, 255, 255}
+	}
+
+	aliased := RenderOptions{Aliased: true}
+	for _, r := range []Renderer{Draw2D{aliased}, Scanline{aliased}, Vector{aliased}} {
+		img := renderWith(c, r).img
+		for i, v := range img.Pix {
+			if v != 0 && v != 255 {
+				t.Fatalf("%s: expected only fully covered or uncovered pixels, got %d at offset %d", r.Name(), v, i)
+			}
+		}
+	}
+}
+
+func TestRendererFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	newRenderer := RendererFlags(fs)
+
+	if err := fs.Parse([]string{"-renderer", "vector", "-aa=false"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := newRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Vector{RenderOptions{Aliased: true}}); r != expected {
+		t.Errorf("expected %+v, got: %+v", expected, r)
+	}
+
+	if err := fs.Parse([]string{"-fill", "evenodd"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newRenderer(); err == nil {
+		t.Errorf("expected error for the vector renderer with the even-odd fill rule")
+	}
+}
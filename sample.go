@@ -0,0 +1,100 @@
+package polygen
+
+import (
+	"image"
+	"log"
+	"math"
+	"math/rand"
+)
+
+const (
+	// while sampling, a new set of sample pixels is chosen every this many generations
+	SampleResampleEvery = 50
+
+	// DefaultSampleExactAfter is the default for Options.SampleExactAfter
+	DefaultSampleExactAfter = 25
+)
+
+// stratifiedSample chooses roughly fraction of the pixels of a w x h image, by dividing it into square cells and
+// picking one random pixel from each. Returns the indexes of the chosen pixels.
+func stratifiedSample(w, h int, fraction float64) []int {
+	cell := int(math.Round(1 / math.Sqrt(fraction)))
+	if cell < 1 {
+		cell = 1
+	}
+
+	var result []int
+	for y := 0; y < h; y += cell {
+		for x := 0; x < w; x += cell {
+			px := x + rand.Intn(clampInt(w-x, 1, cell))
+			py := y + rand.Intn(clampInt(h-y, 1, cell))
+			result = append(result, py*w+px)
+		}
+	}
+
+	return result
+}
+
+// sampleFitness estimates rf.Fitness(ref, img, weights) from the given pixels only, scaled up to the size of
+// the whole image.
+func sampleFitness(rf regionFitnessFunc, ref, img *image.RGBA, weights []uint8, pixels []int) uint64 {
+	accumError := uint64(0)
+
+	for _, p := range pixels {
+		i := p * 4
+		d := rf.pixelError(ref.Pix[i:i+4], img.Pix[i:i+4])
+
+		if weights != nil {
+			d *= uint64(weights[p])
+		}
+		accumError += d
+	}
+
+	return uint64(float64(accumError) * float64(len(ref.Pix)/4) / float64(len(pixels)) / float64(rawScale(weights)))
+}
+
+// startSampling switches the Evolver to scoring candidates on a sample of the reference pixels, if
+// Options.SampleFraction is set and the configuration allows it.
+func (e *Evolver) startSampling() {
+	f := e.options.SampleFraction
+	if f <= 0 || f >= 1 {
+		return
+	}
+
+	if _, ok := e.metric.(regionFitnessFunc); !ok || e.options.EdgeWeight > 0 || e.options.Linear {
+		log.Printf("pixel sampling is not supported with the %s metric, edge weights or linear mode, using exact fitness", e.metric.Name())
+		return
+	}
+
+	if !e.options.Quiet {
+		log.Printf("scoring candidates on %g of the pixels until %d generations pass without improvement", f, e.sampleExactAfter())
+	}
+
+	e.resample()
+}
+
+// sampleExactAfter returns Options.SampleExactAfter, or its default.
+func (e *Evolver) sampleExactAfter() int {
+	if e.options.SampleExactAfter > 0 {
+		return e.options.SampleExactAfter
+	}
+
+	return DefaultSampleExactAfter
+}
+
+// resample chooses a new set of sample pixels, and re-scores the most fit candidate on them.
+func (e *Evolver) resample() {
+	b := e.refImgRGBA.Bounds()
+	e.sample = stratifiedSample(b.Dx(), b.Dy(), e.options.SampleFraction)
+	e.evaluate(e.mostFit)
+}
+
+// stopSampling switches the Evolver back to exact fitness, and re-scores the most fit candidate.
+func (e *Evolver) stopSampling() {
+	e.sample = nil
+	e.evaluate(e.mostFit)
+
+	if !e.options.Quiet {
+		log.Printf("switched to exact fitness at generation %d: %d", e.generation, e.mostFit.Fitness)
+	}
+}
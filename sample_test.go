@@ -0,0 +1,132 @@
+package polygen
+
+import (
+	"image"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStratifiedSample(t *testing.T) {
+	pixels := stratifiedSample(100, 50, 0.25)
+
+	// cells are 2x2, so one pixel in each
+	if len(pixels) != 50*25 {
+		t.Fatalf("expected %d pixels, got: %d", 50*25, len(pixels))
+	}
+
+	seen := make(map[int]bool)
+	for _, p := range pixels {
+		x, y := p%100, p/100
+		if p < 0 || p >= 100*50 || seen[(y/2)*50+x/2] {
+			t.Fatalf("pixel %d (%d, %d) is out of bounds or in an already sampled cell", p, x, y)
+		}
+		seen[(y/2)*50+x/2] = true
+	}
+
+	// cells that overhang the edge of the image must stay within it
+	for _, p := range stratifiedSample(7, 5, 0.1) {
+		if p < 0 || p >= 7*5 {
+			t.Fatalf("pixel %d is out of bounds", p)
+		}
+	}
+}
+
+func TestSampleFitness(t *testing.T) {
+	ref := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	img := image.NewRGBA(ref.Bounds())
+	for i := range ref.Pix {
+		ref.Pix[i] = uint8(rand.Intn(256))
+		img.Pix[i] = uint8(rand.Intn(256))
+	}
+
+	weights := make([]uint8, 30*20)
+	for i := range weights {
+		weights[i] = uint8(rand.Intn(256))
+	}
+
+	// sampling every pixel should give the exact fitness
+	all := stratifiedSample(30, 20, 1)
+
+	for _, rf := range []regionFitnessFunc{MAE{}, MSE{}} {
+		for _, w := range [][]uint8{nil, weights} {
+			expected, _ := rf.(FitnessFunc).Fitness(ref, img, w)
+			if actual := sampleFitness(rf, ref, img, w, all); actual != expected {
+				t.Errorf("%s: expected %d, got: %d", rf.(FitnessFunc).Name(), expected, actual)
+			}
+		}
+	}
+
+	// a single pixel is scaled up to the whole image
+	expected := MAE{}.pixelError(ref.Pix[0:4], img.Pix[0:4]) * 30 * 20
+	if actual := sampleFitness(MAE{}, ref, img, nil, []int{0}); actual != expected {
+		t.Errorf("expected %d, got: %d", expected, actual)
+	}
+}
+
+func TestSampledRunEndsExact(t *testing.T) {
+	ref := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for i := range ref.Pix {
+		ref.Pix[i] = uint8(rand.Intn(256))
+	}
+
+	e, err := NewEvolver(ref, "", "", Options{Quiet: true, SampleFraction: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Run(100, 5, nil)
+
+	if e.sample != nil {
+		t.Errorf("expected sampling to have stopped")
+	}
+
+	fitness := e.mostFit.Fitness
+	e.renderAndEvaluate(e.mostFit)
+	if e.mostFit.Fitness != fitness {
+		t.Errorf("expected exact final fitness %d, got: %d", e.mostFit.Fitness, fitness)
+	}
+}
+
+func TestSampledCheckpointIsExact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "polygen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ref := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for i := range ref.Pix {
+		ref.Pix[i] = uint8(rand.Intn(256))
+	}
+
+	cpFile := filepath.Join(dir, "cp.tmp")
+	e, err := NewEvolver(ref, "", cpFile, Options{Quiet: true, SampleFraction: 0.1, SampleExactAfter: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.mostFit = randomCandidate(40, 30, 5)
+	e.candidates[0] = e.mostFit
+	e.mostFit.renderImage()
+	e.startSampling()
+	if e.sample == nil {
+		t.Fatal("expected sampling to have started")
+	}
+
+	if err := e.saveCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := LoadCheckpoint(cpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := MAE{}.Fitness(ref, e.mostFit.img, nil)
+	if cp.MostFit.Fitness != expected {
+		t.Errorf("expected the checkpoint to have the exact fitness %d, got: %d", expected, cp.MostFit.Fitness)
+	}
+}
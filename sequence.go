@@ -0,0 +1,136 @@
+package polygen
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var frameNumberRegexp = regexp.MustCompile(`(\d+)\D*$`)
+
+// SequenceOptions controls EvolveSequence.
+type SequenceOptions struct {
+	Polygons         int // number of polygons in every frame
+	FirstGenerations int // generations to evolve the first frame from scratch
+	Generations      int // generations to evolve each following frame, starting from the previous one
+}
+
+// SortFrames sorts image file names by the last number in each name (so that "frame9.png" comes before
+// "frame10.png"), falling back to the names themselves. Names without a number come after all numbered ones.
+func SortFrames(files []string) {
+	sort.SliceStable(files, func(i, j int) bool {
+		ni, iok := frameNumber(files[i])
+		nj, jok := frameNumber(files[j])
+
+		if iok != jok {
+			return iok
+		}
+		if iok && ni != nj {
+			return ni < nj
+		}
+
+		return files[i] < files[j]
+	})
+}
+
+func frameNumber(file string) (int, bool) {
+	base := filepath.Base(file)
+	m := frameNumberRegexp.FindStringSubmatch(strings.TrimSuffix(base, filepath.Ext(base)))
+	if m == nil {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(m[1])
+	return n, err == nil
+}
+
+// EvolveSequence turns a sequence of frames into a polygon animation. The first frame is evolved from a
+// random Candidate, and each following frame is warm-started from the best Candidate of the frame before it.
+// Polygons are never reordered, so polygon i describes the same shape in every frame. For each frame, the
+// rendered image and the genome (as a checkpoint) are written to outDir. Frames that already have a complete
+// checkpoint in outDir are not evolved again, so an interrupted run can be resumed.
+func EvolveSequence(frames []string, outDir string, seqOptions SequenceOptions, options Options) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to evolve")
+	}
+
+	// outputs are named after the frame without its extension, so e.g. a.jpg and a.png would collide
+	sources := make(map[string]string)
+	for _, frame := range frames {
+		name := frameName(frame)
+		if other, ok := sources[name]; ok {
+			return fmt.Errorf("%s and %s would both be saved as %s in %s, rename one of them", other, frame, name, outDir)
+		}
+		sources[name] = frame
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	options.StableOrder = true
+
+	var prev *Candidate
+	var size image.Rectangle
+
+	for i, frame := range frames {
+		name := frameName(frame)
+		dst := filepath.Join(outDir, name+".png")
+		cpFile := filepath.Join(outDir, name+"-checkpoint.tmp")
+
+		budget := seqOptions.Generations
+		if prev == nil {
+			budget = seqOptions.FirstGenerations
+		}
+
+		if cp, err := LoadCheckpoint(cpFile); err == nil && cp.Generation >= budget {
+			if err := cp.checkRenderer(cpFile, options.Renderer); err != nil {
+				return err
+			}
+
+			log.Printf("frame %d/%d (%s) already complete, fitness: %d", i+1, len(frames), frame, cp.MostFit.Fitness)
+			prev = cp.MostFit
+			size = image.Rect(0, 0, prev.W, prev.H)
+			continue
+		}
+
+		refImg, err := ReadImage(frame)
+		if err != nil {
+			return fmt.Errorf("error reading frame %d/%d: %s", i+1, len(frames), err)
+		}
+		if prev != nil && refImg.Bounds().Size() != size.Size() {
+			return fmt.Errorf("frame %s is %v, expected all frames to be %v", frame, refImg.Bounds().Size(), size.Size())
+		}
+		size = refImg.Bounds()
+
+		e, err := NewEvolver(refImg, dst, cpFile, options)
+		if err != nil {
+			return err
+		}
+
+		// warm start from the previous frame, unless we are resuming this one
+		if prev != nil && e.mostFit == nil {
+			e.mostFit = prev.copyOf()
+			e.candidates[0] = e.mostFit
+		}
+
+		e.Run(budget, seqOptions.Polygons, nil)
+		prev = e.mostFit
+
+		log.Printf("frame %d/%d (%s) done, fitness: %d", i+1, len(frames), frame, prev.Fitness)
+	}
+
+	return nil
+}
+
+// frameName returns the name of the output image and checkpoint for frame, without extension.
+func frameName(frame string) string {
+	base := filepath.Base(frame)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
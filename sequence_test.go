@@ -0,0 +1,68 @@
+package polygen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSortFrames(t *testing.T) {
+	frames := []string{"out/frame10.png", "out/frame9.png", "out/frame100.png", "out/frame1.png", "out/title.png"}
+	SortFrames(frames)
+
+	expected := []string{"out/frame1.png", "out/frame9.png", "out/frame10.png", "out/frame100.png", "out/title.png"}
+	if !reflect.DeepEqual(frames, expected) {
+		t.Fatalf("expected %v, got: %v", expected, frames)
+	}
+
+	// unnumbered names sort after numbered ones, even when they would come first by name
+	mixed := []string{"z2.png", "a.png", "b10.png", "cover.png", "m1.png"}
+	SortFrames(mixed)
+
+	expected = []string{"m1.png", "z2.png", "b10.png", "a.png", "cover.png"}
+	if !reflect.DeepEqual(mixed, expected) {
+		t.Fatalf("expected %v, got: %v", expected, mixed)
+	}
+}
+
+// without the z-order mutation, each polygon should stay at its index
+func TestMutateStableOrder(t *testing.T) {
+	c := randomCandidate(100, 100, 10)
+	original := append([]*Polygon(nil), c.Polygons...)
+
+	mutations := []int{MutationColor, MutationPoint, MutationAlpha, MutationAddOrDeletePoint}
+	for i := 0; i < 1000; i++ {
+		c.mutate(mutations)
+	}
+
+	for i := range original {
+		if c.Polygons[i] != original[i] {
+			t.Fatalf("polygon %d was moved", i)
+		}
+	}
+}
+
+func TestEvolveSequenceErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "polygen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	options := SequenceOptions{Polygons: 5, FirstGenerations: 10, Generations: 10}
+
+	// a.jpg and a.png would both be saved as a.png
+	if err := EvolveSequence([]string{"in/a.jpg", "in/a.png"}, dir, options, Options{Quiet: true}); err == nil {
+		t.Errorf("expected error for colliding frame names")
+	}
+
+	bogus := filepath.Join(dir, "bogus.png")
+	if err := ioutil.WriteFile(bogus, []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := EvolveSequence([]string{bogus}, filepath.Join(dir, "out"), options, Options{Quiet: true}); err == nil {
+		t.Errorf("expected error for an unreadable frame")
+	}
+}
@@ -2,6 +2,7 @@ package polygen
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"image"
@@ -106,10 +107,23 @@ func serveNonCacheableImage(img image.Image, w http.ResponseWriter, r *http.Requ
 	}
 }
 
-func Serve(hostPort string, refImg image.Image, previews []*SafeImage) {
+// statusHandler serves the current Status as JSON.
+func statusHandler(status *SafeStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-control", "max-age=0, must-revalidate")
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(status.Value()); err != nil {
+			log.Printf("unable to write status: %s", err)
+		}
+	}
+}
+
+func Serve(hostPort string, refImg image.Image, previews []*SafeImage, status *SafeStatus) {
 	http.Handle("/", rootHandler(len(previews)))
 	http.Handle("/image/", imageHandler(previews))
 	http.Handle("/ref", refImageHandler(refImg))
+	http.Handle("/status", statusHandler(status))
 
 	log.Printf("listening on %s...", hostPort)
 
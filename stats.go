@@ -10,6 +10,7 @@ type Stats struct {
 	startTime           time.Time
 	prevTime            time.Time
 	candidatesEvaluated int
+	recycled            int
 }
 
 func NewStats() *Stats {
@@ -23,7 +24,12 @@ func (s *Stats) Increment(count int) {
 	s.candidatesEvaluated += count
 }
 
-func (s *Stats) Print(best, worst *Candidate, generation, generationsSinceChange int) {
+// Recycled records that count degenerate polygons have been respawned.
+func (s *Stats) Recycled(count int) {
+	s.recycled += count
+}
+
+func (s *Stats) Print(best, worst *Candidate, quality Quality, generation, generationsSinceChange int) {
 	timeNow := time.Now()
 	durOverall := timeNow.Sub(s.startTime)
 
@@ -33,5 +39,5 @@ func (s *Stats) Print(best, worst *Candidate, generation, generationsSinceChange
 	s.prevTime = timeNow
 	s.candidatesEvaluated = 0
 
-	log.Printf("dur: %s, gen: %d, since change: %d, candidates/sec: %.2f, best: %d, worst: %d", durOverall, generation, generationsSinceChange, cps, best.Fitness, worst.Fitness)
+	log.Printf("dur: %s, gen: %d, since change: %d, candidates/sec: %.2f, best: %d (%s), worst: %d, recycled: %d", durOverall, generation, generationsSinceChange, cps, best.Fitness, quality, worst.Fitness, s.recycled)
 }
@@ -0,0 +1,67 @@
+package polygen
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+)
+
+// WriteSVG writes the Candidate to w as an SVG document with the Candidate's dimensions as its viewBox: a black
+// background rect (unless the Candidate is Transparent), followed by one <polygon> per Polygon, in z-order.
+// Anti-aliasing and the fill rule follow the Candidate's Renderer. Browsers blend in sRGB, so Linear candidates
+// will look slightly different than their PNG renders.
+func WriteSVG(w io.Writer, cd *Candidate) error {
+	bw := bufio.NewWriter(w)
+	options := effectiveRenderOptions(cd.Renderer())
+
+	fillRule := "evenodd"
+	if options.FillRule == FillNonZero {
+		fillRule = "nonzero"
+	}
+
+	shapeRendering := "auto"
+	if options.Aliased {
+		shapeRendering = "crispEdges"
+	}
+
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", cd.W, cd.H, cd.W, cd.H)
+	fmt.Fprintf(bw, "<g fill-rule=\"%s\" shape-rendering=\"%s\">\n", fillRule, shapeRendering)
+
+	if !cd.Transparent {
+		fmt.Fprintf(bw, "<rect width=\"%d\" height=\"%d\" fill=\"#000000\"/>\n", cd.W, cd.H)
+	}
+
+	for _, poly := range cd.Polygons {
+		c := color.NRGBAModel.Convert(poly.Color).(color.NRGBA)
+
+		fmt.Fprint(bw, "<polygon points=\"")
+		for i, p := range poly.Points {
+			if i > 0 {
+				fmt.Fprint(bw, " ")
+			}
+			fmt.Fprintf(bw, "%d,%d", p.X, p.Y)
+		}
+		fmt.Fprintf(bw, "\" fill=\"#%02x%02x%02x\" fill-opacity=\"%.3f\"/>\n", c.R, c.G, c.B, float64(c.A)/255)
+	}
+
+	fmt.Fprint(bw, "</g>\n</svg>\n")
+
+	return bw.Flush()
+}
+
+// SaveSVG writes the Candidate to the given file in SVG format, see WriteSVG.
+func SaveSVG(file string, cd *Candidate) error {
+	outfile, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteSVG(outfile, cd); err != nil {
+		outfile.Close()
+		return err
+	}
+
+	return outfile.Close()
+}
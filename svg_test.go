@@ -0,0 +1,68 @@
+package polygen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestWriteSVG(t *testing.T) {
+	c := &Candidate{W: 40, H: 30, Polygons: []*Polygon{
+		{Points: []Point{{1, 2}, {30, 4}, {10, 25}}, Color: color.RGBAModel.Convert(color.NRGBA{255, 0, 255, 51})},
+		{Points: []Point{{5, 5}, {35, 5}, {35, 25}, {5, 25}}, Color: color.RGBA{0, 0, 255, 255}},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		ViewBox string `xml:"viewBox,attr"`
+		G       struct {
+			FillRule string `xml:"fill-rule,attr"`
+			Rects    []struct {
+				Fill string `xml:"fill,attr"`
+			} `xml:"rect"`
+			Polygons []struct {
+				Points      string `xml:"points,attr"`
+				Fill        string `xml:"fill,attr"`
+				FillOpacity string `xml:"fill-opacity,attr"`
+			} `xml:"polygon"`
+		} `xml:"g"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid SVG: %s\n%s", err, buf.String())
+	}
+
+	if doc.ViewBox != "0 0 40 30" {
+		t.Errorf("expected viewBox 0 0 40 30, got: %q", doc.ViewBox)
+	}
+	if doc.G.FillRule != "evenodd" {
+		t.Errorf("expected fill-rule evenodd, got: %q", doc.G.FillRule)
+	}
+	if len(doc.G.Rects) != 1 || doc.G.Rects[0].Fill != "#000000" {
+		t.Errorf("expected a black background rect, got: %+v", doc.G.Rects)
+	}
+	if len(doc.G.Polygons) != 2 {
+		t.Fatalf("expected 2 polygons, got: %d", len(doc.G.Polygons))
+	}
+
+	p := doc.G.Polygons[0]
+	if p.Points != "1,2 30,4 10,25" || p.Fill != "#ff00ff" || p.FillOpacity != "0.200" {
+		t.Errorf("unexpected first polygon: %+v", p)
+	}
+
+	// no background when transparent, and the fill rule follows the renderer
+	c.Transparent = true
+	c.SetRenderer(Vector{})
+	buf.Reset()
+	if err := WriteSVG(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); strings.Contains(s, "<rect") || !strings.Contains(s, `fill-rule="nonzero"`) {
+		t.Errorf("expected a transparent, nonzero SVG, got:\n%s", s)
+	}
+}
@@ -0,0 +1,351 @@
+package polygen
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+)
+
+const (
+	// minSeamWeight is the fitness weight given to the outermost overlap pixels of a tile. Weights ramp up
+	// linearly from here to full weight at the edge of the tile's core.
+	minSeamWeight = 32
+)
+
+// TileOptions controls EvolveTiled.
+type TileOptions struct {
+	Size        int      // width & height of each tile, not including overlap
+	Overlap     int      // number of pixels each tile extends into its neighbors
+	Polygons    int      // number of polygons per tile
+	Generations int      // number of generations to evolve each tile
+	Transparent bool     // evolve on a transparent canvas, see Options.Transparent
+	Renderer    Renderer // draws the tiles and the stitched result, see Options.Renderer
+}
+
+// tile is a region of the reference image that is evolved independently. The tile is responsible for its
+// core, but is evaluated over bounds, which extends core into its neighbors so that the seams blend.
+type tile struct {
+	core, bounds image.Rectangle
+}
+
+// tileLayout splits r into a grid of size x size tiles, each extended by overlap pixels, clipped to r.
+func tileLayout(r image.Rectangle, size, overlap int) []tile {
+	var result []tile
+
+	for y := r.Min.Y; y < r.Max.Y; y += size {
+		for x := r.Min.X; x < r.Max.X; x += size {
+			core := image.Rect(x, y, x+size, y+size).Intersect(r)
+			bounds := image.Rect(core.Min.X-overlap, core.Min.Y-overlap, core.Max.X+overlap, core.Max.Y+overlap).Intersect(r)
+			result = append(result, tile{core: core, bounds: bounds})
+		}
+	}
+
+	return result
+}
+
+// seamWeights returns a weight map for t (in tile-local coordinates, see Options.WeightMap). Pixels in the core
+// count fully, and the weights of the overlap fall off with the distance from the core, so that a tile matches
+// its neighbors at the seams without fighting them.
+func (t tile) seamWeights(overlap int) *image.Gray {
+	w, h := t.bounds.Dx(), t.bounds.Dy()
+	result := image.NewGray(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := image.Pt(x, y).Add(t.bounds.Min)
+
+			dist := 0
+			if d := t.core.Min.X - p.X; d > dist {
+				dist = d
+			}
+			if d := p.X - (t.core.Max.X - 1); d > dist {
+				dist = d
+			}
+			if d := t.core.Min.Y - p.Y; d > dist {
+				dist = d
+			}
+			if d := p.Y - (t.core.Max.Y - 1); d > dist {
+				dist = d
+			}
+
+			weight := 255
+			if dist > 0 {
+				weight = 255 - (255-minSeamWeight)*dist/overlap
+			}
+			result.Pix[y*w+x] = uint8(weight)
+		}
+	}
+
+	return result
+}
+
+// EvolveTiled approximates a (potentially large) reference image by splitting it into overlapping tiles,
+// evolving a Candidate for each tile in parallel, and stitching the results together into a single
+// Candidate covering the whole image. If dstImageFile or checkPointFile are non-empty, the stitched
+// result is saved there. If checkPointFile exists, each tile resumes from its polygons in the checkpoint,
+// which must have been saved by EvolveTiled with the same Size, Overlap and Polygons.
+func EvolveTiled(refImg image.Image, dstImageFile, checkPointFile string, options TileOptions) (*Candidate, error) {
+	if options.Size <= 0 || options.Overlap < 0 || options.Polygons <= 0 {
+		return nil, fmt.Errorf("invalid tile options: %+v", options)
+	}
+
+	ref := ConvertToRGBA(refImg)
+	tiles := tileLayout(ref.Bounds(), options.Size, options.Overlap)
+
+	cp, starts, err := resumeTiles(checkPointFile, ref.Bounds(), tiles, options.Polygons, options.Renderer)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("evolving %d tiles of %dx%d (overlap %d) with %d polygons each", len(tiles), options.Size, options.Size, options.Overlap, options.Polygons)
+
+	generation := 0
+	if cp != nil {
+		log.Printf("resuming from checkpoint file %s at generation %d", checkPointFile, cp.Generation)
+		generation = cp.Generation
+	}
+
+	results, err := evolveTiles(ref, tiles, starts, generation, options)
+	if err != nil {
+		return nil, err
+	}
+
+	result := stitch(ref.Bounds(), tiles, results)
+	result.Transparent = options.Transparent
+	result.renderer = options.Renderer
+	result.renderImage()
+
+	fitness, err := FastCompare(ref, result.img)
+	if err != nil {
+		return nil, err
+	}
+	result.Fitness = fitness
+
+	if dstImageFile != "" {
+		if err := result.drawAndSave(dstImageFile); err != nil {
+			return nil, fmt.Errorf("error saving output image: %s", err)
+		}
+	}
+
+	if checkPointFile != "" {
+		generation := options.Generations
+		if cp != nil && cp.Generation > generation {
+			generation = cp.Generation
+		}
+
+		if err := SaveCheckpoint(checkPointFile, &Checkpoint{Generation: generation, MostFit: result, Metric: (MAE{}).Name()}); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// evolveTiles evolves a Candidate for each of the tiles of ref in parallel, starting from starts (and
+// generation) if non-nil. The candidates are in tile-local coordinates.
+func evolveTiles(ref *image.RGBA, tiles []tile, starts []*Candidate, generation int, options TileOptions) ([]*Candidate, error) {
+	results := make([]*Candidate, len(tiles))
+	errs := make([]error, len(tiles))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	for i, t := range tiles {
+		wg.Add(1)
+
+		go func(i int, t tile) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			tileRef := image.NewRGBA(image.Rect(0, 0, t.bounds.Dx(), t.bounds.Dy()))
+			draw.Draw(tileRef, tileRef.Bounds(), ref, t.bounds.Min, draw.Src)
+
+			e, err := NewEvolver(tileRef, "", "", Options{Quiet: true, WeightMap: t.seamWeights(options.Overlap), Transparent: options.Transparent, Renderer: options.Renderer})
+			if err != nil {
+				errs[i] = fmt.Errorf("error creating evolver for tile %d: %s", i, err)
+				return
+			}
+
+			if starts != nil {
+				e.generation = generation
+				e.mostFit = starts[i]
+				e.candidates[0] = e.mostFit
+			}
+
+			e.Run(options.Generations, options.Polygons, nil)
+			results[i] = e.mostFit
+
+			log.Printf("tile %d/%d at %v done, fitness: %d", i+1, len(tiles), t.core, e.mostFit.Fitness)
+		}(i, t)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// resumeTiles loads checkPointFile, if it exists, and splits its Candidate back into one per tile (see
+// unstitch). Returns a nil Checkpoint if there is nothing to resume from.
+func resumeTiles(checkPointFile string, r image.Rectangle, tiles []tile, polygons int, renderer Renderer) (*Checkpoint, []*Candidate, error) {
+	if checkPointFile == "" {
+		return nil, nil, nil
+	}
+	if _, err := os.Stat(checkPointFile); os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+
+	cp, err := LoadCheckpoint(checkPointFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// tiles are always evolved with the default metric
+	cpMetric, err := ParseFitnessFunc(cp.Metric)
+	if err != nil {
+		return nil, nil, fmt.Errorf("checkpoint file %s: %s", checkPointFile, err)
+	}
+	if cpMetric.Name() != (MAE{}).Name() {
+		return nil, nil, fmt.Errorf("checkpoint file %s was evolved with the %s metric, not %s", checkPointFile, cpMetric.Name(), (MAE{}).Name())
+	}
+
+	if err := cp.checkRenderer(checkPointFile, renderer); err != nil {
+		return nil, nil, err
+	}
+
+	c := cp.MostFit
+	if c.W != r.Dx() || c.H != r.Dy() || len(c.Polygons) != len(tiles)*polygons {
+		return nil, nil, fmt.Errorf("checkpoint file %s has %d polygons at %dx%d, expected %d tiles of %d polygons at %dx%d", checkPointFile, len(c.Polygons), c.W, c.H, len(tiles), polygons, r.Dx(), r.Dy())
+	}
+
+	return cp, unstitch(r, tiles, c), nil
+}
+
+// stitch combines the per-tile candidates into one Candidate, translating each tile's polygons into the
+// coordinate space of r. Each tile was only responsible for its core, and its polygons were evaluated over black
+// (or transparent) rather than over its neighbors, so they are clipped to the core (see clipPolygon): otherwise
+// the overlap painted by later tiles would cover the cores of earlier ones. The number of polygons per tile is
+// kept, so that unstitch can split the result again.
+func stitch(r image.Rectangle, tiles []tile, candidates []*Candidate) *Candidate {
+	result := &Candidate{W: r.Dx(), H: r.Dy()}
+
+	for i, t := range tiles {
+		offset := t.bounds.Min.Sub(r.Min)
+		core := t.core.Sub(r.Min)
+
+		for _, poly := range candidates[i].Polygons {
+			p := poly.copyOf()
+			for j := range p.Points {
+				p.Points[j].X += offset.X
+				p.Points[j].Y += offset.Y
+			}
+			p.Points = clipPolygon(p.Points, core)
+			result.Polygons = append(result.Polygons, p)
+		}
+	}
+
+	return result
+}
+
+// clipPolygon clips points to r with the Sutherland-Hodgman algorithm, rounding the new vertices to the nearest
+// pixel. Since the edges of r lie between pixels, the clipped polygon covers the same pixels within r as the
+// original, up to that rounding. If nothing is left, it returns a degenerate polygon of MinPolygonPoints
+// identical points, which covers no pixels at all.
+func clipPolygon(points []Point, r image.Rectangle) []Point {
+	type edge struct {
+		inside    func(x, y float64) bool
+		intersect func(x1, y1, x2, y2 float64) (float64, float64)
+	}
+
+	atX := func(x float64) func(x1, y1, x2, y2 float64) (float64, float64) {
+		return func(x1, y1, x2, y2 float64) (float64, float64) { return x, y1 + (x-x1)*(y2-y1)/(x2-x1) }
+	}
+	atY := func(y float64) func(x1, y1, x2, y2 float64) (float64, float64) {
+		return func(x1, y1, x2, y2 float64) (float64, float64) { return x1 + (y-y1)*(x2-x1)/(y2-y1), y }
+	}
+
+	minX, minY, maxX, maxY := float64(r.Min.X), float64(r.Min.Y), float64(r.Max.X), float64(r.Max.Y)
+	edges := []edge{
+		{func(x, y float64) bool { return x >= minX }, atX(minX)},
+		{func(x, y float64) bool { return x <= maxX }, atX(maxX)},
+		{func(x, y float64) bool { return y >= minY }, atY(minY)},
+		{func(x, y float64) bool { return y <= maxY }, atY(maxY)},
+	}
+
+	type vertex struct{ x, y float64 }
+	poly := make([]vertex, len(points))
+	for i, p := range points {
+		poly[i] = vertex{float64(p.X), float64(p.Y)}
+	}
+
+	for _, e := range edges {
+		var clipped []vertex
+		for i, cur := range poly {
+			prev := poly[(i+len(poly)-1)%len(poly)]
+			curIn, prevIn := e.inside(cur.x, cur.y), e.inside(prev.x, prev.y)
+
+			if curIn != prevIn {
+				x, y := e.intersect(prev.x, prev.y, cur.x, cur.y)
+				clipped = append(clipped, vertex{x, y})
+			}
+			if curIn {
+				clipped = append(clipped, cur)
+			}
+		}
+		poly = clipped
+	}
+
+	var result []Point
+	for _, v := range poly {
+		p := Point{int(math.Round(v.x)), int(math.Round(v.y))}
+		if len(result) == 0 || p != result[len(result)-1] {
+			result = append(result, p)
+		}
+	}
+	for len(result) > 1 && result[0] == result[len(result)-1] {
+		result = result[:len(result)-1]
+	}
+
+	if len(result) < MinPolygonPoints {
+		p := Point{clampInt(points[0].X, r.Min.X, r.Max.X-1), clampInt(points[0].Y, r.Min.Y, r.Max.Y-1)}
+		result = []Point{p, p, p}
+	}
+
+	return result
+}
+
+// unstitch is the inverse of stitch: it splits c, which must have the same number of polygons for each tile, back
+// into one Candidate per tile, in tile-local coordinates. Points outside of their tile are clamped to it.
+func unstitch(r image.Rectangle, tiles []tile, c *Candidate) []*Candidate {
+	n := len(c.Polygons) / len(tiles)
+	result := make([]*Candidate, len(tiles))
+
+	for i, t := range tiles {
+		offset := t.bounds.Min.Sub(r.Min)
+		cd := &Candidate{W: t.bounds.Dx(), H: t.bounds.Dy()}
+
+		for _, poly := range c.Polygons[i*n : (i+1)*n] {
+			p := poly.copyOf()
+			for j := range p.Points {
+				p.Points[j].X = clampInt(p.Points[j].X-offset.X, 0, cd.W-1)
+				p.Points[j].Y = clampInt(p.Points[j].Y-offset.Y, 0, cd.H-1)
+			}
+			cd.Polygons = append(cd.Polygons, p)
+		}
+
+		result[i] = cd
+	}
+
+	return result
+}
@@ -0,0 +1,164 @@
+package polygen
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTileLayout(t *testing.T) {
+	r := image.Rect(0, 0, 250, 100)
+	tiles := tileLayout(r, 100, 10)
+
+	if len(tiles) != 3 {
+		t.Fatalf("expected 3 tiles, got: %d", len(tiles))
+	}
+
+	// the cores should exactly cover the image, without overlapping
+	covered := 0
+	for _, tl := range tiles {
+		covered += tl.core.Dx() * tl.core.Dy()
+
+		if !tl.core.In(tl.bounds) || !tl.bounds.In(r) {
+			t.Fatalf("bad tile: %+v", tl)
+		}
+	}
+
+	if covered != r.Dx()*r.Dy() {
+		t.Fatalf("expected cores to cover %d pixels, got: %d", r.Dx()*r.Dy(), covered)
+	}
+
+	expected := image.Rect(90, 0, 210, 100)
+	if tiles[1].bounds != expected {
+		t.Fatalf("expected middle tile bounds to be %v, got: %v", expected, tiles[1].bounds)
+	}
+}
+
+func TestSeamWeights(t *testing.T) {
+	tl := tile{core: image.Rect(10, 0, 20, 10), bounds: image.Rect(0, 0, 30, 10)}
+	weights := tl.seamWeights(10).Pix
+
+	if weights[15] != 255 {
+		t.Errorf("expected full weight in core, got: %d", weights[15])
+	}
+
+	// outermost overlap pixel on either side
+	if weights[0] != minSeamWeight || weights[29] != minSeamWeight {
+		t.Errorf("expected %d at the edges, got: %d, %d", minSeamWeight, weights[0], weights[29])
+	}
+
+	if weights[5] <= weights[0] || weights[5] >= weights[15] {
+		t.Errorf("expected weights to ramp up towards the core, got: %d", weights[5])
+	}
+}
+
+func TestStitch(t *testing.T) {
+	r := image.Rect(0, 0, 200, 100)
+	tiles := tileLayout(r, 100, 10)
+
+	var candidates []*Candidate
+	for _, tl := range tiles {
+		candidates = append(candidates, randomCandidate(tl.bounds.Dx(), tl.bounds.Dy(), 5))
+	}
+
+	result := stitch(r, tiles, candidates)
+	if result.W != 200 || result.H != 100 || len(result.Polygons) != 10 {
+		t.Fatalf("unexpected stitched candidate: %dx%d, %d polygons", result.W, result.H, len(result.Polygons))
+	}
+
+	// the second tile's points must have been shifted into its bounds
+	for _, poly := range result.Polygons[5:] {
+		for _, p := range poly.Points {
+			if !image.Pt(p.X, p.Y).In(tiles[1].bounds) {
+				t.Fatalf("point %+v outside of tile bounds %v", p, tiles[1].bounds)
+			}
+		}
+	}
+}
+
+func TestUnstitch(t *testing.T) {
+	r := image.Rect(0, 0, 200, 100)
+	tiles := tileLayout(r, 100, 10)
+
+	var candidates []*Candidate
+	for _, tl := range tiles {
+		candidates = append(candidates, randomCandidate(tl.bounds.Dx(), tl.bounds.Dy(), 5))
+	}
+
+	// stitching clips the polygons to the cores, so a stitched candidate survives a round trip unchanged
+	stitched := stitch(r, tiles, candidates)
+	split := unstitch(r, tiles, stitched)
+
+	for i, c := range split {
+		if c.W != candidates[i].W || c.H != candidates[i].H || len(c.Polygons) != 5 {
+			t.Fatalf("tile %d: unexpected candidate: %dx%d, %d polygons", i, c.W, c.H, len(c.Polygons))
+		}
+	}
+
+	for i, poly := range stitch(r, tiles, split).Polygons {
+		if !reflect.DeepEqual(poly.Points, stitched.Polygons[i].Points) {
+			t.Fatalf("polygon %d: expected points %v, got: %v", i, stitched.Polygons[i].Points, poly.Points)
+		}
+	}
+}
+
+func TestClipPolygon(t *testing.T) {
+	r := image.Rect(10, 10, 20, 20)
+
+	inside := []Point{{12, 12}, {18, 13}, {15, 19}}
+	if clipped := clipPolygon(inside, r); !reflect.DeepEqual(clipped, inside) {
+		t.Errorf("expected a polygon inside r to be unchanged, got: %v", clipped)
+	}
+
+	// a square straddling the right edge of r is cut off at x = 20
+	square := []Point{{15, 12}, {25, 12}, {25, 18}, {15, 18}}
+	expected := []Point{{15, 12}, {20, 12}, {20, 18}, {15, 18}}
+	if clipped := clipPolygon(square, r); !reflect.DeepEqual(clipped, expected) {
+		t.Errorf("expected %v, got: %v", expected, clipped)
+	}
+
+	// nothing is left of a polygon outside r, but the number of polygons must be kept
+	outside := []Point{{30, 30}, {40, 30}, {35, 40}}
+	clipped := clipPolygon(outside, r)
+	if len(clipped) != MinPolygonPoints || clipped[0] != clipped[1] || clipped[1] != clipped[2] || !image.Pt(clipped[0].X, clipped[0].Y).In(r) {
+		t.Errorf("expected a degenerate polygon within r, got: %v", clipped)
+	}
+}
+
+func TestEvolveTiledSeams(t *testing.T) {
+	ref := ConvertToRGBA(MustReadImage("images/mona_lisa.jpg"))
+	options := TileOptions{Size: 60, Overlap: 8, Polygons: 10, Generations: 100}
+	tiles := tileLayout(ref.Bounds(), options.Size, options.Overlap)
+
+	results, err := evolveTiles(ref, tiles, nil, 0, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the error of each tile within its core, as evolved
+	tileError := uint64(0)
+	for i, tl := range tiles {
+		results[i].renderImage()
+
+		tileRef := image.NewRGBA(image.Rect(0, 0, tl.bounds.Dx(), tl.bounds.Dy()))
+		draw.Draw(tileRef, tileRef.Bounds(), ref, tl.bounds.Min, draw.Src)
+
+		d, _ := regionError(tileRef, results[i].img, nil, tl.core.Sub(tl.bounds.Min), nil, math.MaxUint64, MAE{}.pixelError)
+		tileError += d
+	}
+
+	stitched := stitch(ref.Bounds(), tiles, results)
+	stitched.renderImage()
+	stitchedError, err := FastCompare(ref, stitched.img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// clipping rounds the new vertices to whole pixels, which costs a little accuracy, but tiles must no longer
+	// paint over each other's cores
+	if limit := tileError + tileError/100; stitchedError > limit {
+		t.Errorf("expected the stitched error to be no worse than the error of the tiles, %d, got: %d", tileError, stitchedError)
+	}
+}
@@ -36,3 +36,16 @@ func DeriveCheckpointFile(sourceFile, cpArg string, polyCount int) string {
 
 	return fmt.Sprintf("%s-%d-checkpoint.tmp", name, polyCount)
 }
+
+// DeriveTiledCheckpointFile is like DeriveCheckpointFile, but for EvolveTiled, whose checkpoints hold polyCount
+// polygons per tile, and so must not be picked up by a regular run (or one with a different tile layout).
+func DeriveTiledCheckpointFile(sourceFile, cpArg string, polyCount, tileSize, overlap int) string {
+	if cpArg != "" {
+		return cpArg
+	}
+
+	basename := path.Base(sourceFile)
+	name := strings.TrimSuffix(basename, filepath.Ext(basename))
+
+	return fmt.Sprintf("%s-%d-tile%d-overlap%d-checkpoint.tmp", name, polyCount, tileSize, overlap)
+}
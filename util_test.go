@@ -39,3 +39,13 @@ func TestDeriveCheckpointFile(t *testing.T) {
 		}
 	}
 }
+
+func TestDeriveTiledCheckpointFile(t *testing.T) {
+	if actual, expected := DeriveTiledCheckpointFile("images/foo.jpg", "", 50, 100, 8), "foo-50-tile100-overlap8-checkpoint.tmp"; actual != expected {
+		t.Errorf("wanted %s, got: %s", expected, actual)
+	}
+
+	if actual := DeriveTiledCheckpointFile("images/foo.jpg", "custom.tmp", 50, 100, 8); actual != "custom.tmp" {
+		t.Errorf("wanted custom.tmp, got: %s", actual)
+	}
+}